@@ -0,0 +1,40 @@
+package basic
+
+import (
+	_ "embed"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/percona/rds_exporter/config"
+)
+
+//go:embed metrics.yml
+var defaultCatalog []byte
+
+// Metrics is the default basic metrics catalog.
+// Configuration file catalog entries override it.
+var Metrics = mustParseCatalog(defaultCatalog)
+
+// mustParseCatalog parses and validates catalog file, or panics.
+func mustParseCatalog(b []byte) []config.CatalogMetric {
+	var catalog struct {
+		Metrics []config.CatalogMetric `yaml:"metrics"`
+	}
+	if err := yaml.UnmarshalStrict(b, &catalog); err != nil {
+		panic(fmt.Sprintf("failed to parse basic metrics catalog: %s", err))
+	}
+
+	seen := make(map[string]bool, len(catalog.Metrics))
+	for _, m := range catalog.Metrics {
+		if err := m.Validate(); err != nil {
+			panic(fmt.Sprintf("invalid basic metrics catalog: %s", err))
+		}
+		if seen[m.Key()] {
+			panic(fmt.Sprintf("invalid basic metrics catalog: duplicate metric %s", m.Key()))
+		}
+		seen[m.Key()] = true
+	}
+
+	return catalog.Metrics
+}
@@ -1,20 +1,21 @@
 package basic
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/health"
 	"github.com/percona/rds_exporter/sessions"
 )
 
-//go:generate go run generate/main.go generate/utils.go
-
 var (
 	scrapeTimeDesc = prometheus.NewDesc(
 		"rds_exporter_scrape_duration_seconds",
@@ -24,29 +25,68 @@ var (
 	)
 )
 
-type Metric struct {
-	cwName         string
-	prometheusName string
-	prometheusHelp string
-}
-
 type Collector struct {
+	l log.Logger
+
+	rw       sync.RWMutex
 	config   *config.Config
 	sessions *sessions.Sessions
-	metrics  []Metric
-	l        log.Logger
+	metrics  []config.CatalogMetric
+
+	status *health.Tracker
+
+	ctx    context.Context // canceled by Close
+	cancel context.CancelFunc
 }
 
 // New creates a new instance of a Collector.
-func New(config *config.Config, sessions *sessions.Sessions, logger log.Logger) *Collector {
+func New(cfg *config.Config, sessions *sessions.Sessions, logger log.Logger) *Collector {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Collector{
-		config:   config,
+		config:   cfg,
 		sessions: sessions,
-		metrics:  Metrics,
+		metrics:  config.MergeCatalog(Metrics, cfg.Catalog),
 		l:        log.With(logger, "component", "basic"),
+		status:   health.NewTracker("basic"),
+		ctx:      ctx,
+		cancel:   cancel,
 	}
 }
 
+// Close cancels in-flight and following scrapes.
+func (e *Collector) Close() {
+	e.cancel()
+}
+
+// Update replaces configuration, metrics catalog and sessions used by the following scrapes.
+func (e *Collector) Update(cfg *config.Config, sessions *sessions.Sessions) {
+	metrics := config.MergeCatalog(Metrics, cfg.Catalog)
+
+	e.rw.Lock()
+	e.config = cfg
+	e.sessions = sessions
+	e.metrics = metrics
+	e.rw.Unlock()
+
+	// remove status of instances that are not scraped anymore
+	enabled := make(map[string]bool)
+	for _, instances := range sessions.AllSessions() {
+		for _, instance := range instances {
+			if !instance.DisableBasicMetrics {
+				enabled[instance.Region+"/"+instance.Instance] = true
+			}
+		}
+	}
+	e.status.Retain(func(region, instance string) bool {
+		return enabled[region+"/"+instance]
+	})
+}
+
+// Status returns the latest scrape status of given instance, or false if it is unknown.
+func (e *Collector) Status(region, instance string) (health.Status, bool) {
+	return e.status.Status(region, instance)
+}
+
 func (e *Collector) Describe(ch chan<- *prometheus.Desc) {
 	// unchecked collector
 }
@@ -54,35 +94,67 @@ func (e *Collector) Describe(ch chan<- *prometheus.Desc) {
 func (e *Collector) Collect(ch chan<- prometheus.Metric) {
 	now := time.Now()
 	e.collect(ch)
+	e.status.Collect(ch)
 
 	// Collect scrape time
 	ch <- prometheus.MustNewConstMetric(scrapeTimeDesc, prometheus.GaugeValue, time.Since(now).Seconds())
 }
 
 func (e *Collector) collect(ch chan<- prometheus.Metric) {
+	e.rw.RLock()
+	cfg := e.config
+	sess := e.sessions
+	metrics := e.metrics
+	e.rw.RUnlock()
+
 	var wg sync.WaitGroup
 	defer wg.Wait()
 
-	for _, instance := range e.config.Instances {
-		if instance.DisableBasicMetrics {
-			level.Debug(e.l).Log("msg", fmt.Sprintf("Instance %s has disabled basic metrics, skipping.", instance))
+	for session, awsCfg := range sess.Configs {
+		instances := sess.AllSessions()[session]
+		enabledInstances := make([]sessions.Instance, 0, len(instances))
+		for _, instance := range instances {
+			if instance.DisableBasicMetrics {
+				level.Debug(e.l).Log("msg", fmt.Sprintf("Instance %s has disabled basic metrics, skipping.", instance))
+				continue
+			}
+			enabledInstances = append(enabledInstances, instance)
+		}
+		clusters := sess.AllClusters()[session]
+		if len(enabledInstances) == 0 && len(clusters) == 0 {
 			continue
 		}
-		instance := instance
+
+		awsCfg := awsCfg
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 
-			s := NewScraper(&instance, e, ch)
-			if s == nil {
-				level.Error(e.l).Log("msg", fmt.Sprintf("No scraper for %s, skipping.", instance))
-				return
-			}
-			s.Scrape()
+			s := NewScraper(awsCfg, enabledInstances, clusters, metrics, cfg.Basic, e, ch)
+			s.status = e.status
+			_ = s.ScrapeContext(e.ctx)
 		}()
 	}
 }
 
+// Probe scrapes basic metrics of a single instance using given AWS config, and sends them to the channel.
+// Failed CloudWatch queries are reported as an error too.
+func (e *Collector) Probe(ctx context.Context, awsCfg aws.Config, instance sessions.Instance, ch chan<- prometheus.Metric) error {
+	e.rw.RLock()
+	cfg := e.config
+	metrics := e.metrics
+	e.rw.RUnlock()
+
+	s := NewScraper(awsCfg, []sessions.Instance{instance}, nil, metrics, cfg.Basic, e, ch)
+	if err := s.ScrapeContext(ctx); err != nil {
+		return err
+	}
+	if s.failed[&s.instances[0]] {
+		return fmt.Errorf("failed to get metric data for some queries of %s", &s.instances[0])
+	}
+	return nil
+}
+
 // check interfaces
 var (
 	_ prometheus.Collector = (*Collector)(nil)
@@ -21,7 +21,7 @@ func TestCollector(t *testing.T) {
 	require.NoError(t, err)
 	logger := promlog.New(&promlog.Config{})
 	client := client.New(logger)
-	sess, err := sessions.New(cfg.Instances, client.HTTP(), logger, false)
+	sess, err := sessions.New(cfg.Instances, cfg.Clusters, client.HTTP(), logger, false)
 	require.NoError(t, err)
 
 	c := New(cfg, sess, logger)
@@ -65,7 +65,7 @@ func TestCollectorDisableBasicMetrics(t *testing.T) {
 		// Groups instance names by disabled or enabled metrics.
 		instanceGroups[isDisabled] = append(instanceGroups[isDisabled], cfg.Instances[i].Instance)
 	}
-	sess, err := sessions.New(cfg.Instances, client.HTTP(), logger, false)
+	sess, err := sessions.New(cfg.Instances, cfg.Clusters, client.HTTP(), logger, false)
 	require.NoError(t, err)
 
 	c := New(cfg, sess, logger)
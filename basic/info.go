@@ -0,0 +1,63 @@
+package basic
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/percona/rds_exporter/sessions"
+)
+
+// makeInfoMetrics returns instance metadata metrics that don't require CloudWatch requests.
+// Nothing is returned if instance metadata was not described.
+func makeInfoMetrics(instance *sessions.Instance) []prometheus.Metric {
+	info := &instance.Info
+	if *info == (sessions.InstanceInfo{}) {
+		return nil
+	}
+
+	infoLabels := makeConstLabels(prometheus.Labels{
+		"region":            instance.Region,
+		"instance":          instance.Instance,
+		"engine":            info.Engine,
+		"engine_version":    info.EngineVersion,
+		"instance_class":    info.InstanceClass,
+		"storage_type":      info.StorageType,
+		"multi_az":          strconv.FormatBool(info.MultiAZ),
+		"availability_zone": info.AvailabilityZone,
+		"cluster":           info.ClusterID,
+		"parameter_group":   info.ParameterGroup,
+		"ca_certificate":    info.CACertificate,
+	}, instance.Labels)
+	constLabels := makeConstLabels(prometheus.Labels{
+		"region":   instance.Region,
+		"instance": instance.Instance,
+	}, instance.Labels)
+
+	res := make([]prometheus.Metric, 0, 6)
+	res = append(res, prometheus.MustNewConstMetric(
+		prometheus.NewDesc("aws_rds_instance_info", "RDS instance metadata. Value is always 1.", nil, infoLabels),
+		prometheus.GaugeValue,
+		1,
+	))
+
+	for _, m := range []struct {
+		name  string
+		help  string
+		value float64
+	}{
+		{"aws_rds_allocated_storage_bytes", "The allocated storage size.", float64(info.AllocatedStorage) * 1024 * 1024 * 1024},
+		{"aws_rds_max_allocated_storage_bytes", "The upper limit for storage autoscaling; zero if autoscaling is disabled.", float64(info.MaxAllocatedStorage) * 1024 * 1024 * 1024},
+		{"aws_rds_provisioned_iops", "The provisioned IOPS value; zero if not set.", float64(info.IOPS)},
+		{"aws_rds_storage_throughput_bytes", "The provisioned storage throughput per second; zero if not set.", float64(info.StorageThroughput) * 1024 * 1024},
+		{"aws_rds_backup_retention_period_seconds", "The period for which automated backups are retained.", float64(info.BackupRetentionPeriod) * 24 * 60 * 60},
+	} {
+		res = append(res, prometheus.MustNewConstMetric(
+			prometheus.NewDesc(m.name, m.help, nil, constLabels),
+			prometheus.GaugeValue,
+			m.value,
+		))
+	}
+
+	return res
+}
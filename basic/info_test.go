@@ -0,0 +1,63 @@
+package basic
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/percona/exporter_shared/helpers"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/percona/rds_exporter/sessions"
+)
+
+func TestInfoMetrics(t *testing.T) {
+	instance := &sessions.Instance{
+		Region:   "us-east-1",
+		Instance: "test-mysql",
+		Labels:   map[string]string{"foo": "bar", "cluster": ""},
+		Info: sessions.InstanceInfo{
+			Engine:                "mysql",
+			EngineVersion:         "8.0.35",
+			InstanceClass:         "db.m5.large",
+			StorageType:           "gp3",
+			MultiAZ:               true,
+			AvailabilityZone:      "us-east-1a",
+			ParameterGroup:        "default.mysql8.0",
+			CACertificate:         "rds-ca-rsa2048-g1",
+			AllocatedStorage:      100,
+			MaxAllocatedStorage:   200,
+			IOPS:                  3000,
+			StorageThroughput:     125,
+			BackupRetentionPeriod: 7,
+		},
+	}
+
+	actual := helpers.ReadMetrics(makeInfoMetrics(instance))
+	sort.Slice(actual, func(i, j int) bool { return actual[i].Less(actual[j]) })
+	actualLines := helpers.Format(helpers.WriteMetrics(actual))
+
+	expectedLines := []string{
+		`# HELP aws_rds_allocated_storage_bytes The allocated storage size.`,
+		`# TYPE aws_rds_allocated_storage_bytes gauge`,
+		`aws_rds_allocated_storage_bytes{foo="bar",instance="test-mysql",region="us-east-1"} 1.073741824e+11`,
+		`# HELP aws_rds_backup_retention_period_seconds The period for which automated backups are retained.`,
+		`# TYPE aws_rds_backup_retention_period_seconds gauge`,
+		`aws_rds_backup_retention_period_seconds{foo="bar",instance="test-mysql",region="us-east-1"} 604800`,
+		`# HELP aws_rds_instance_info RDS instance metadata. Value is always 1.`,
+		`# TYPE aws_rds_instance_info gauge`,
+		`aws_rds_instance_info{availability_zone="us-east-1a",ca_certificate="rds-ca-rsa2048-g1",engine="mysql",engine_version="8.0.35",foo="bar",instance="test-mysql",instance_class="db.m5.large",multi_az="true",parameter_group="default.mysql8.0",region="us-east-1",storage_type="gp3"} 1`,
+		`# HELP aws_rds_max_allocated_storage_bytes The upper limit for storage autoscaling; zero if autoscaling is disabled.`,
+		`# TYPE aws_rds_max_allocated_storage_bytes gauge`,
+		`aws_rds_max_allocated_storage_bytes{foo="bar",instance="test-mysql",region="us-east-1"} 2.147483648e+11`,
+		`# HELP aws_rds_provisioned_iops The provisioned IOPS value; zero if not set.`,
+		`# TYPE aws_rds_provisioned_iops gauge`,
+		`aws_rds_provisioned_iops{foo="bar",instance="test-mysql",region="us-east-1"} 3000`,
+		`# HELP aws_rds_storage_throughput_bytes The provisioned storage throughput per second; zero if not set.`,
+		`# TYPE aws_rds_storage_throughput_bytes gauge`,
+		`aws_rds_storage_throughput_bytes{foo="bar",instance="test-mysql",region="us-east-1"} 1.31072e+08`,
+	}
+	assert.Equal(t, expectedLines, actualLines)
+
+	instance.Info = sessions.InstanceInfo{}
+	assert.Empty(t, makeInfoMetrics(instance))
+}
@@ -2,133 +2,529 @@ package basic
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdsTypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/health"
+	"github.com/percona/rds_exporter/sessions"
 )
 
-var (
-	Period = 60 * time.Second
-	Delay  = 600 * time.Second
-	Range  = 600 * time.Second
-)
+// DefaultStatistics are used for basic metrics when statistics are not configured.
+var DefaultStatistics = []string{string(types.StatisticAverage)}
+
+// DefaultTiming is used for basic metrics when it is not configured.
+var DefaultTiming = config.Timing{
+	Period: 60 * time.Second,
+	Delay:  600 * time.Second,
+	Range:  600 * time.Second,
+}
+
+// GetMetricData request supports up to 500 queries.
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/API_GetMetricData.html
+const maxQueriesPerRequest = 500
+
+// Aurora cluster roles used as Role dimension values.
+var clusterRoles = []string{"WRITER", "READER"}
+
+// query links GetMetricData query ID to the instance or cluster and metric it was made for.
+type query struct {
+	target      fmt.Stringer
+	metric      config.CatalogMetric
+	dimensions  []types.Dimension
+	constLabels prometheus.Labels
+	timing      config.Timing
+	statistic   string
+	name        string // Prometheus metric name for that statistic
+}
 
+// window is a query time range relative to the current time.
+// All queries of a single GetMetricData request share it.
+type window struct {
+	delay time.Duration
+	rng   time.Duration
+}
+
+// datapoint is the latest value received for a single query.
+type datapoint struct {
+	timestamp time.Time
+	value     float64
+}
+
+// Scraper retrieves basic metrics from several RDS instances and Aurora clusters sharing a single session.
 type Scraper struct {
 	// params
-	instance  *config.Instance
+	instances []sessions.Instance
+	clusters  []sessions.Cluster
+	metrics   []config.CatalogMetric
+	basic     config.Basic
 	collector *Collector
 	ch        chan<- prometheus.Metric
 
 	// internal
-	svc         *cloudwatch.Client
-	constLabels prometheus.Labels
+	svc    *cloudwatch.Client
+	rdsSvc *rds.Client
+	status *health.Tracker // may be nil
+
+	m      sync.Mutex
+	failed map[*sessions.Instance]bool
 }
 
-func NewScraper(instance *config.Instance, collector *Collector, ch chan<- prometheus.Metric) *Scraper {
-	cfg, _ := collector.sessions.GetConfig(instance.Region, instance.Instance)
-	if cfg == nil {
-		return nil
-	}
-	svc := cloudwatch.NewFromConfig(*cfg)
+func NewScraper(cfg aws.Config, instances []sessions.Instance, clusters []sessions.Cluster, metrics []config.CatalogMetric, basic config.Basic, collector *Collector, ch chan<- prometheus.Metric) *Scraper {
+	return &Scraper{
+		// params
+		instances: instances,
+		clusters:  clusters,
+		metrics:   metrics,
+		basic:     basic,
+		collector: collector,
+		ch:        ch,
 
-	constLabels := prometheus.Labels{
-		"region":   instance.Region,
-		"instance": instance.Instance,
+		// internal
+		svc:    cloudwatch.NewFromConfig(cfg),
+		rdsSvc: rds.NewFromConfig(cfg),
+		failed: make(map[*sessions.Instance]bool),
 	}
-	for n, v := range instance.Labels {
+}
+
+// makeConstLabels returns given labels with extra labels added, replaced, or removed (for empty values).
+func makeConstLabels(constLabels prometheus.Labels, labels map[string]string) prometheus.Labels {
+	for n, v := range labels {
 		if v == "" {
 			delete(constLabels, n)
 		} else {
 			constLabels[n] = v
 		}
 	}
+	return constLabels
+}
 
-	return &Scraper{
-		// params
-		instance:  instance,
-		collector: collector,
-		ch:        ch,
+// timing returns query timing for given metric of an instance or a cluster with given configuration.
+// Instance or cluster configuration takes precedence over the global one.
+func (s *Scraper) timing(basic config.Basic, metric string) config.Timing {
+	t := basic.Resolve(metric, s.basic.Resolve(metric, DefaultTiming))
 
-		// internal
-		svc:         svc,
-		constLabels: constLabels,
+	// inherited values may be inconsistent even if each level is valid
+	if t.Range < t.Period {
+		t.Range = t.Period
 	}
+	return t
 }
 
-func getLatestDatapoint(datapoints []types.Datapoint) *types.Datapoint {
-	var latest *types.Datapoint = nil
-	for i := range datapoints {
-		if latest == nil || latest.Timestamp.Before(*datapoints[i].Timestamp) {
-			latest = &datapoints[i]
+// statistics returns query statistics for given metric of an instance or a cluster with given configuration.
+// Instance or cluster configuration takes precedence over the global one, and then over the catalog.
+func (s *Scraper) statistics(basic config.Basic, metric config.CatalogMetric) []string {
+	defaults := metric.Statistics
+	if len(defaults) == 0 {
+		defaults = DefaultStatistics
+	}
+	return basic.ResolveStatistics(metric.CWName, s.basic.ResolveStatistics(metric.CWName, defaults))
+}
+
+// statisticSuffix returns Prometheus metric name suffix for given CloudWatch statistic:
+// average, sample_count, p99_9, tm_10_90, etc.
+func statisticSuffix(statistic string) string {
+	if statistic == string(types.StatisticSampleCount) {
+		return "sample_count"
+	}
+
+	var res strings.Builder
+	for _, r := range strings.ToLower(statistic) {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+			res.WriteRune(r)
+		case res.Len() != 0 && !strings.HasSuffix(res.String(), "_"):
+			res.WriteRune('_')
+		}
+	}
+	return strings.TrimSuffix(res.String(), "_")
+}
+
+// metricName returns Prometheus metric name and labels for given metric and statistic.
+// By default, statistic is added as a suffix instead of "_average" (node_boot_time_seconds doesn't have it);
+// with statistic label enabled, "_average" suffix is removed and statistic label is added instead.
+func (s *Scraper) metricName(metric config.CatalogMetric, statistic string, constLabels prometheus.Labels) (string, prometheus.Labels) {
+	base := strings.TrimSuffix(metric.PrometheusName, "_average")
+
+	if s.basic.StatisticLabel {
+		labels := make(prometheus.Labels, len(constLabels)+1)
+		for n, v := range constLabels {
+			labels[n] = v
 		}
+		labels["statistic"] = statistic
+		return base, labels
+	}
+
+	if statistic == string(types.StatisticAverage) {
+		return metric.PrometheusName, constLabels
 	}
-	return latest
+	return base + "_" + statisticSuffix(statistic), constLabels
+}
+
+// makeQueries returns GetMetricData queries for all metrics of all instances and clusters.
+func (s *Scraper) makeQueries() map[string]*query {
+	queries := make(map[string]*query) // query ID -> query
+
+	// add adds queries for all statistics configured for the instance's or cluster's metric
+	add := func(q query, basic config.Basic) {
+		q.timing = s.timing(basic, q.metric.CWName)
+		for _, statistic := range s.statistics(basic, q.metric) {
+			q := q
+			q.statistic = statistic
+			q.name, q.constLabels = s.metricName(q.metric, statistic, q.constLabels)
+
+			// ID must start with a lowercase letter and be unique within a single request
+			queries[fmt.Sprintf("q%d", len(queries))] = &q
+		}
+	}
+
+	for i := range s.instances {
+		instance := &s.instances[i]
+		constLabels := makeConstLabels(prometheus.Labels{
+			"region":   instance.Region,
+			"instance": instance.Instance,
+		}, instance.Labels)
+		dimensions := []types.Dimension{{
+			Name:  aws.String(config.DimensionInstance),
+			Value: aws.String(instance.Instance),
+		}}
+
+		for _, metric := range s.metrics {
+			if d := strings.Join(metric.Dimensions, ","); d != "" && d != config.DimensionInstance {
+				continue
+			}
+
+			add(query{
+				target:      instance,
+				metric:      metric,
+				dimensions:  dimensions,
+				constLabels: constLabels,
+			}, instance.Basic)
+		}
+	}
+
+	for i := range s.clusters {
+		cluster := &s.clusters[i]
+		for _, metric := range s.metrics {
+			switch strings.Join(metric.Dimensions, ",") {
+			case config.DimensionCluster:
+				add(query{
+					target: cluster,
+					metric: metric,
+					dimensions: []types.Dimension{{
+						Name:  aws.String(config.DimensionCluster),
+						Value: aws.String(cluster.Cluster),
+					}},
+					constLabels: makeConstLabels(prometheus.Labels{
+						"region":  cluster.Region,
+						"cluster": cluster.Cluster,
+					}, cluster.Labels),
+				}, cluster.Basic)
+				continue
+
+			case config.DimensionCluster + "," + config.DimensionRole:
+				// handled below
+
+			default:
+				continue
+			}
+
+			for _, role := range clusterRoles {
+				add(query{
+					target: cluster,
+					metric: metric,
+					dimensions: []types.Dimension{{
+						Name:  aws.String(config.DimensionCluster),
+						Value: aws.String(cluster.Cluster),
+					}, {
+						Name:  aws.String(config.DimensionRole),
+						Value: aws.String(role),
+					}},
+					constLabels: makeConstLabels(prometheus.Labels{
+						"region":  cluster.Region,
+						"cluster": cluster.Cluster,
+						"role":    strings.ToLower(role),
+					}, cluster.Labels),
+				}, cluster.Basic)
+			}
+		}
+	}
+
+	return queries
 }
 
 // Scrape makes the required calls to AWS CloudWatch by using the parameters in the Collector.
+// Queries for all metrics of all instances and clusters with the same delay and range
+// are batched into as few GetMetricData requests as possible.
 // Once converted into Prometheus format, the metrics are pushed on the ch channel.
 func (s *Scraper) Scrape() {
+	_ = s.ScrapeContext(context.Background())
+}
+
+// ScrapeContext is like Scrape, but uses given context for AWS requests.
+// It returns all request errors; metrics received before them are sent anyway.
+func (s *Scraper) ScrapeContext(ctx context.Context) error {
+	for i := range s.instances {
+		for _, m := range makeInfoMetrics(&s.instances[i]) {
+			s.ch <- m
+		}
+	}
+
+	queries := s.makeQueries()
+	var windows []window // in order of appearance
+	dataQueries := make(map[window][]types.MetricDataQuery)
+	for i := 0; i < len(queries); i++ {
+		id := fmt.Sprintf("q%d", i)
+		q := queries[id]
+		w := window{delay: q.timing.Delay, rng: q.timing.Range}
+		if _, ok := dataQueries[w]; !ok {
+			windows = append(windows, w)
+		}
+		dataQueries[w] = append(dataQueries[w], types.MetricDataQuery{
+			Id: aws.String(id),
+			MetricStat: &types.MetricStat{
+				Metric: &types.Metric{
+					MetricName: aws.String(q.metric.CWName),
+					Namespace:  aws.String(q.metric.GetNamespace()),
+					Dimensions: q.dimensions,
+				},
+				Period: aws.Int32(int32(q.timing.Period.Seconds())),
+				Stat:   aws.String(q.statistic),
+			},
+			ReturnData: aws.Bool(true),
+		})
+	}
+
 	var wg sync.WaitGroup
-	defer wg.Wait()
+	var errsM sync.Mutex
+	var errs []error
+	addErr := func(err error) {
+		errsM.Lock()
+		errs = append(errs, err)
+		errsM.Unlock()
+	}
 
-	wg.Add(len(s.collector.metrics))
-	for _, metric := range s.collector.metrics {
-		metric := metric
+	if len(s.clusters) > 0 {
+		wg.Add(1)
 		go func() {
 			defer wg.Done()
 
-			if err := s.scrapeMetric(metric); err != nil {
-				level.Error(s.collector.l).Log("metric", metric.cwName, "error", err)
+			if err := s.scrapeClusterMembers(ctx); err != nil {
+				level.Error(s.collector.l).Log("msg", "Failed to describe clusters.", "error", err)
+				addErr(err)
 			}
 		}()
 	}
+
+	for _, w := range windows {
+		for i := 0; i < len(dataQueries[w]); i += maxQueriesPerRequest {
+			end := i + maxQueriesPerRequest
+			if end > len(dataQueries[w]) {
+				end = len(dataQueries[w])
+			}
+			w, batch := w, dataQueries[w][i:end]
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				if err := s.scrapeBatch(ctx, w, batch, queries); err != nil {
+					level.Error(s.collector.l).Log("msg", "Failed to get metric data.", "queries", len(batch), "error", err)
+					addErr(err)
+
+					code := health.ErrorCode(err)
+					seen := make(map[fmt.Stringer]bool)
+					for _, dq := range batch {
+						if q := queries[aws.ToString(dq.Id)]; !seen[q.target] {
+							seen[q.target] = true
+							s.fail(q.target, "", code)
+						}
+					}
+				}
+			}()
+		}
+	}
+
+	wg.Wait()
+
+	if s.status != nil {
+		for i := range s.instances {
+			s.status.Success(s.instances[i].Region, s.instances[i].Instance, !s.failed[&s.instances[i]])
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
-func (s *Scraper) scrapeMetric(metric Metric) error {
-	now := time.Now()
-	end := now.Add(-Delay)
-
-	params := &cloudwatch.GetMetricStatisticsInput{
-		EndTime:    aws.Time(end),
-		StartTime:  aws.Time(end.Add(-Range)),
-		Period:     aws.Int32(int32(Period.Seconds())),
-		MetricName: aws.String(metric.cwName),
-		Namespace:  aws.String("AWS/RDS"),
-		Dimensions: []types.Dimension{{
-			Name:  aws.String("DBInstanceIdentifier"),
-			Value: aws.String(s.instance.Instance),
+// fail records a scrape error for given target and CloudWatch metric name (empty for all metrics).
+// Errors for clusters are only logged.
+func (s *Scraper) fail(target fmt.Stringer, metric, code string) {
+	instance, ok := target.(*sessions.Instance)
+	if !ok {
+		return
+	}
+
+	s.m.Lock()
+	s.failed[instance] = true
+	s.m.Unlock()
+
+	if s.status != nil {
+		s.status.Error(instance.Region, instance.Instance, metric, code)
+	}
+}
+
+// scrapeClusterMembers sends cluster membership metrics.
+func (s *Scraper) scrapeClusterMembers(ctx context.Context) error {
+	clusters := make(map[string]*sessions.Cluster, len(s.clusters))
+	ids := make([]string, 0, len(s.clusters))
+	for i := range s.clusters {
+		clusters[s.clusters[i].Cluster] = &s.clusters[i]
+		ids = append(ids, s.clusters[i].Cluster)
+	}
+
+	paginator := rds.NewDescribeDBClustersPaginator(s.rdsSvc, &rds.DescribeDBClustersInput{
+		Filters: []rdsTypes.Filter{{
+			Name:   aws.String("db-cluster-id"),
+			Values: ids,
 		}},
-		Statistics: []types.Statistic{types.StatisticAverage},
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, dbCluster := range output.DBClusters {
+			cluster := clusters[aws.ToString(dbCluster.DBClusterIdentifier)]
+			if cluster == nil {
+				continue
+			}
+
+			for _, member := range dbCluster.DBClusterMembers {
+				role := "reader"
+				if aws.ToBool(member.IsClusterWriter) {
+					role = "writer"
+				}
+				constLabels := makeConstLabels(prometheus.Labels{
+					"region":   cluster.Region,
+					"cluster":  cluster.Cluster,
+					"instance": aws.ToString(member.DBInstanceIdentifier),
+					"role":     role,
+				}, cluster.Labels)
+
+				s.ch <- prometheus.MustNewConstMetric(
+					prometheus.NewDesc("aws_rds_cluster_member_info", "Aurora cluster member instance and its role. Value is always 1.", nil, constLabels),
+					prometheus.GaugeValue,
+					1,
+				)
+			}
+		}
 	}
 
-	resp, err := s.svc.GetMetricStatistics(context.Background(), params)
-	if err != nil {
-		return err
+	return nil
+}
+
+// scrapeBatch performs a single GetMetricData request (with all its pages) and sends the latest value for every query.
+// Metrics received before an error are sent too.
+func (s *Scraper) scrapeBatch(ctx context.Context, w window, dataQueries []types.MetricDataQuery, queries map[string]*query) error {
+	now := time.Now()
+	end := now.Add(-w.delay)
+
+	input := &cloudwatch.GetMetricDataInput{
+		EndTime:           aws.Time(end),
+		StartTime:         aws.Time(end.Add(-w.rng)),
+		MetricDataQueries: dataQueries,
+		ScanBy:            types.ScanByTimestampDescending,
 	}
 
-	if len(resp.Datapoints) == 0 {
-		return nil
+	latest := make(map[string]datapoint) // query ID -> latest datapoint
+	defer func() {
+		for id, dp := range latest {
+			s.sendMetric(queries[id], dp.value)
+		}
+	}()
+
+	paginator := cloudwatch.NewGetMetricDataPaginator(s.svc, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range output.Messages {
+			level.Warn(s.collector.l).Log("msg", aws.ToString(m.Value), "code", aws.ToString(m.Code))
+		}
+
+		for _, result := range output.MetricDataResults {
+			id := aws.ToString(result.Id)
+			q := queries[id]
+			if q == nil {
+				level.Error(s.collector.l).Log("msg", fmt.Sprintf("Unexpected query ID %q in response.", id))
+				continue
+			}
+
+			switch result.StatusCode {
+			case types.StatusCodeInternalError, types.StatusCodeForbidden:
+				l := log.With(s.collector.l, "metric", q.metric.CWName, "statistic", q.statistic, "target", q.target, "status", result.StatusCode)
+				code := string(result.StatusCode)
+				for _, m := range result.Messages {
+					l = log.With(l, aws.ToString(m.Code), aws.ToString(m.Value))
+					if aws.ToString(m.Code) != "" {
+						code = aws.ToString(m.Code)
+					}
+				}
+				level.Error(l).Log("msg", "Failed to get metric data for query.")
+				s.fail(q.target, q.metric.CWName, code)
+				continue
+			}
+
+			for i, timestamp := range result.Timestamps {
+				if i >= len(result.Values) {
+					break
+				}
+				if dp, ok := latest[id]; !ok || dp.timestamp.Before(timestamp) {
+					latest[id] = datapoint{timestamp: timestamp, value: result.Values[i]}
+				}
+			}
+		}
 	}
 
-	dp := getLatestDatapoint(resp.Datapoints)
-	v := aws.ToFloat64(dp.Average)
-	switch metric.cwName {
-	case "EngineUptime":
-		v = float64(time.Now().Unix() - int64(v))
+	return nil
+}
+
+func (s *Scraper) sendMetric(q *query, v float64) {
+	if q.metric.Scale != 0 {
+		v *= q.metric.Scale
+	}
+
+	switch q.metric.Transform {
+	case config.TransformBootTime:
+		// uptime statistics are converted to boot time; counts are not
+		if q.statistic != string(types.StatisticSampleCount) {
+			v = float64(time.Now().Unix() - int64(v))
+		}
+	}
+
+	help := q.metric.PrometheusHelp
+	if help == "" {
+		help = q.metric.CWName
 	}
 
 	s.ch <- prometheus.MustNewConstMetric(
-		prometheus.NewDesc(metric.prometheusName, metric.prometheusHelp, nil, s.constLabels),
+		prometheus.NewDesc(q.name, help, nil, q.constLabels),
 		prometheus.GaugeValue,
 		v,
 	)
-
-	return nil
 }
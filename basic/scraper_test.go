@@ -0,0 +1,516 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/percona/exporter_shared/helpers"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/promlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/health"
+	"github.com/percona/rds_exporter/sessions"
+)
+
+// fakeCloudWatch implements a subset of CloudWatch GetMetricData API (query protocol).
+// It returns up to pageSize results per page, and Forbidden status for forbidden metric names.
+// It also implements RDS DescribeDBClusters API that returns a writer and a reader for every requested cluster.
+type fakeCloudWatch struct {
+	pageSize  int
+	forbidden map[string]string // instance -> metric name
+
+	rw       sync.Mutex
+	requests int                      // total HTTP requests
+	batches  int                      // requests without NextToken
+	timings  map[string]config.Timing // instance/metric name -> received timing
+}
+
+func (f *fakeCloudWatch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch action := r.PostForm.Get("Action"); action {
+	case "GetMetricData":
+		// handled below
+	case "DescribeDBClusters":
+		f.describeDBClusters(w, r)
+		return
+	default:
+		http.Error(w, "unexpected action "+action, http.StatusBadRequest)
+		return
+	}
+
+	start, _ := strconv.Atoi(r.PostForm.Get("NextToken"))
+	f.rw.Lock()
+	f.requests++
+	if start == 0 {
+		f.batches++
+	}
+	f.rw.Unlock()
+
+	end, _ := time.Parse(time.RFC3339, r.PostForm.Get("EndTime"))
+	startTime, _ := time.Parse(time.RFC3339, r.PostForm.Get("StartTime"))
+	delay := time.Since(end).Round(time.Minute)
+
+	var results strings.Builder
+	var n int
+	for n = start + 1; n <= start+f.pageSize; n++ {
+		prefix := fmt.Sprintf("MetricDataQueries.member.%d.", n)
+		id := r.PostForm.Get(prefix + "Id")
+		if id == "" {
+			break
+		}
+		name := r.PostForm.Get(prefix + "MetricStat.Metric.MetricName")
+		instance := r.PostForm.Get(prefix + "MetricStat.Metric.Dimensions.member.1.Value")
+		period, _ := strconv.Atoi(r.PostForm.Get(prefix + "MetricStat.Period"))
+
+		f.rw.Lock()
+		if f.timings == nil {
+			f.timings = make(map[string]config.Timing)
+		}
+		f.timings[instance+"/"+name] = config.Timing{
+			Period: time.Duration(period) * time.Second,
+			Delay:  delay,
+			Range:  end.Sub(startTime),
+		}
+		f.rw.Unlock()
+
+		if f.forbidden[instance] == name {
+			fmt.Fprintf(&results, `<member><Id>%s</Id><StatusCode>Forbidden</StatusCode>`+
+				`<Messages><member><Code>Forbidden</Code><Value>Access denied</Value></member></Messages>`+
+				`<Timestamps/><Values/></member>`, id)
+			continue
+		}
+
+		// older datapoint first to check that the latest one is used
+		fmt.Fprintf(&results, `<member><Id>%s</Id><Label>%s</Label><StatusCode>Complete</StatusCode>`+
+			`<Timestamps><member>%s</member><member>%s</member></Timestamps>`+
+			`<Values><member>1</member><member>42</member></Values></member>`,
+			id, name, end.Add(-2*time.Minute).Format(time.RFC3339), end.Add(-time.Minute).Format(time.RFC3339))
+	}
+
+	var nextToken string
+	if r.PostForm.Get(fmt.Sprintf("MetricDataQueries.member.%d.Id", n)) != "" {
+		nextToken = fmt.Sprintf("<NextToken>%d</NextToken>", n-1)
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprintf(w, `<GetMetricDataResponse xmlns="http://monitoring.amazonaws.com/doc/2010-08-01/">`+
+		`<GetMetricDataResult><MetricDataResults>%s</MetricDataResults>%s<Messages/></GetMetricDataResult>`+
+		`<ResponseMetadata><RequestId>00000000-0000-0000-0000-000000000000</RequestId></ResponseMetadata>`+
+		`</GetMetricDataResponse>`, results.String(), nextToken)
+}
+
+func (f *fakeCloudWatch) describeDBClusters(w http.ResponseWriter, r *http.Request) {
+	var clusters strings.Builder
+	for i := 1; r.PostForm.Get(fmt.Sprintf("Filters.Filter.1.Values.Value.%d", i)) != ""; i++ {
+		cluster := r.PostForm.Get(fmt.Sprintf("Filters.Filter.1.Values.Value.%d", i))
+		fmt.Fprintf(&clusters, `<DBCluster><DBClusterIdentifier>%[1]s</DBClusterIdentifier><DBClusterMembers>`+
+			`<DBClusterMember><DBInstanceIdentifier>%[1]s-1</DBInstanceIdentifier><IsClusterWriter>true</IsClusterWriter></DBClusterMember>`+
+			`<DBClusterMember><DBInstanceIdentifier>%[1]s-2</DBInstanceIdentifier><IsClusterWriter>false</IsClusterWriter></DBClusterMember>`+
+			`</DBClusterMembers></DBCluster>`, cluster)
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprintf(w, `<DescribeDBClustersResponse xmlns="http://rds.amazonaws.com/doc/2014-10-31/">`+
+		`<DescribeDBClustersResult><DBClusters>%s</DBClusters></DescribeDBClustersResult>`+
+		`<ResponseMetadata><RequestId>00000000-0000-0000-0000-000000000000</RequestId></ResponseMetadata>`+
+		`</DescribeDBClustersResponse>`, clusters.String())
+}
+
+// newFakeConfig returns AWS config for given fake endpoint.
+func newFakeConfig(srv *httptest.Server) aws.Config {
+	return aws.Config{
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		BaseEndpoint: aws.String(srv.URL),
+		HTTPClient:   srv.Client(),
+	}
+}
+
+// scrape runs scraper and returns all sent metrics.
+func scrape(s *Scraper, ch chan prometheus.Metric) []prometheus.Metric {
+	go func() {
+		s.Scrape()
+		close(ch)
+	}()
+
+	var metrics []prometheus.Metric
+	for m := range ch {
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+func TestScraper(t *testing.T) {
+	for _, td := range []struct {
+		instances        int
+		expectedRequests int
+		expectedBatches  int
+	}{
+		{instances: 1, expectedRequests: 1, expectedBatches: 1},
+		{instances: 6, expectedRequests: 3, expectedBatches: 1},
+		{instances: 11, expectedRequests: 6, expectedBatches: 2},
+	} {
+		td := td
+		t.Run(fmt.Sprint(td.instances), func(t *testing.T) {
+			fake := &fakeCloudWatch{
+				pageSize:  100,
+				forbidden: map[string]string{"test-0": "CPUUtilization"},
+			}
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+
+			cfg := newFakeConfig(srv)
+
+			instances := make([]sessions.Instance, td.instances)
+			for i := range instances {
+				instances[i] = sessions.Instance{
+					Region:   "us-east-1",
+					Instance: fmt.Sprintf("test-%d", i),
+					Labels:   map[string]string{"foo": "bar"},
+				}
+			}
+
+			logger := promlog.New(&promlog.Config{})
+			c := &Collector{
+				l: logger,
+			}
+
+			ch := make(chan prometheus.Metric)
+			metrics := scrape(NewScraper(cfg, instances, nil, Metrics, config.Basic{}, c, ch), ch)
+
+			assert.Equal(t, td.expectedRequests, fake.requests)
+			assert.Equal(t, td.expectedBatches, fake.batches)
+			infoNames := make(map[string]bool)
+			for _, m := range helpers.ReadMetrics(makeInfoMetrics(&instances[0])) {
+				infoNames[m.Name] = true
+			}
+			var instanceMetrics int
+			for _, m := range Metrics {
+				if len(m.Dimensions) == 0 {
+					instanceMetrics++
+				}
+			}
+			require.Len(t, metrics, td.instances*(instanceMetrics+len(infoNames))-1)
+
+			for _, m := range helpers.ReadMetrics(metrics) {
+				if infoNames[m.Name] {
+					continue
+				}
+				assert.Equal(t, "us-east-1", m.Labels["region"])
+				assert.Equal(t, "bar", m.Labels["foo"])
+				assert.NotEmpty(t, m.Labels["instance"])
+				if m.Labels["instance"] == "test-0" {
+					assert.NotEqual(t, "aws_rds_cpu_utilization_average", m.Name)
+				}
+				if m.Help != "EngineUptime" {
+					assert.Equal(t, float64(42), m.Value, "%s", m)
+				}
+			}
+		})
+	}
+}
+
+func TestScraperClusters(t *testing.T) {
+	fake := &fakeCloudWatch{
+		pageSize: 100,
+	}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	clusters := []sessions.Cluster{{
+		Region:  "us-east-1",
+		Cluster: "test-cluster",
+		Labels:  map[string]string{"foo": "bar"},
+	}}
+
+	logger := promlog.New(&promlog.Config{})
+	c := &Collector{
+		l: logger,
+	}
+
+	ch := make(chan prometheus.Metric)
+	metrics := helpers.ReadMetrics(scrape(NewScraper(newFakeConfig(srv), nil, clusters, Metrics, config.Basic{}, c, ch), ch))
+
+	var expected int
+	for _, m := range Metrics {
+		switch len(m.Dimensions) {
+		case 1:
+			expected++
+		case 2:
+			expected += len(clusterRoles)
+		}
+	}
+	assert.Equal(t, 1, fake.requests)
+
+	roles := make(map[string]int)
+	members := make(map[string]string)
+	var values int
+	for _, m := range metrics {
+		assert.Equal(t, "us-east-1", m.Labels["region"])
+		assert.Equal(t, "test-cluster", m.Labels["cluster"])
+		assert.Equal(t, "bar", m.Labels["foo"])
+
+		if m.Name == "aws_rds_cluster_member_info" {
+			members[m.Labels["instance"]] = m.Labels["role"]
+			continue
+		}
+		values++
+		roles[m.Labels["role"]]++
+		assert.Equal(t, float64(42), m.Value, "%s", m)
+	}
+	assert.Equal(t, expected, values)
+	assert.Equal(t, roles["writer"], roles["reader"])
+	assert.NotZero(t, roles["writer"])
+	assert.Equal(t, map[string]string{"test-cluster-1": "writer", "test-cluster-2": "reader"}, members)
+}
+
+func TestScraperTiming(t *testing.T) {
+	fake := &fakeCloudWatch{
+		pageSize: 100,
+	}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	instances := []sessions.Instance{{
+		Region:   "us-east-1",
+		Instance: "test-0",
+	}, {
+		Region:   "us-east-1",
+		Instance: "test-1",
+		Basic: config.Basic{
+			Timing: config.Timing{Delay: 2 * time.Minute},
+			Metrics: map[string]config.BasicMetric{
+				"CPUUtilization": {Timing: config.Timing{Period: 10 * time.Second, Range: 5 * time.Second}},
+			},
+		},
+	}}
+	basic := config.Basic{
+		Timing: config.Timing{Period: 2 * time.Minute},
+		Metrics: map[string]config.BasicMetric{
+			"CPUCreditUsage": {Timing: config.Timing{Period: 5 * time.Minute, Range: 15 * time.Minute}},
+		},
+	}
+
+	logger := promlog.New(&promlog.Config{})
+	c := &Collector{
+		l: logger,
+	}
+
+	ch := make(chan prometheus.Metric)
+	scrape(NewScraper(newFakeConfig(srv), instances, nil, Metrics, basic, c, ch), ch)
+
+	// (10m, 10m), (10m, 15m), (2m, 10m), (2m, 15m), (2m, 10s)
+	assert.Equal(t, 5, fake.batches)
+
+	for key, expected := range map[string]config.Timing{
+		"test-0/WriteIOPS":      {Period: 2 * time.Minute, Delay: 10 * time.Minute, Range: 10 * time.Minute},
+		"test-0/CPUCreditUsage": {Period: 5 * time.Minute, Delay: 10 * time.Minute, Range: 15 * time.Minute},
+		"test-0/CPUUtilization": {Period: 2 * time.Minute, Delay: 10 * time.Minute, Range: 10 * time.Minute},
+		"test-1/WriteIOPS":      {Period: 2 * time.Minute, Delay: 2 * time.Minute, Range: 10 * time.Minute},
+		"test-1/CPUCreditUsage": {Period: 5 * time.Minute, Delay: 2 * time.Minute, Range: 15 * time.Minute},
+		"test-1/CPUUtilization": {Period: 10 * time.Second, Delay: 2 * time.Minute, Range: 10 * time.Second},
+	} {
+		assert.Equal(t, expected, fake.timings[key], key)
+	}
+}
+
+func TestScraperStatistics(t *testing.T) {
+	instances := []sessions.Instance{{
+		Region:   "us-east-1",
+		Instance: "test-0",
+		Basic: config.Basic{
+			Metrics: map[string]config.BasicMetric{
+				"WriteLatency": {Statistics: []string{"Average", "Maximum", "SampleCount", "p99.9", "TM(10%:90%)"}},
+			},
+		},
+	}}
+
+	for _, td := range []struct {
+		statisticLabel bool
+		expected       []string // name or name{statistic}
+	}{{
+		expected: []string{
+			"aws_rds_write_latency_average",
+			"aws_rds_write_latency_maximum",
+			"aws_rds_write_latency_sample_count",
+			"aws_rds_write_latency_p99_9",
+			"aws_rds_write_latency_tm_10_90",
+			"aws_rds_read_latency_average",
+		},
+	}, {
+		statisticLabel: true,
+		expected: []string{
+			"aws_rds_write_latency{Average}",
+			"aws_rds_write_latency{Maximum}",
+			"aws_rds_write_latency{SampleCount}",
+			"aws_rds_write_latency{p99.9}",
+			"aws_rds_write_latency{TM(10%:90%)}",
+			"aws_rds_read_latency{Average}",
+		},
+	}} {
+		td := td
+		t.Run(fmt.Sprint(td.statisticLabel), func(t *testing.T) {
+			fake := &fakeCloudWatch{
+				pageSize: 100,
+			}
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+
+			logger := promlog.New(&promlog.Config{})
+			c := &Collector{
+				l: logger,
+			}
+
+			ch := make(chan prometheus.Metric)
+			basic := config.Basic{StatisticLabel: td.statisticLabel}
+			metrics := helpers.ReadMetrics(scrape(NewScraper(newFakeConfig(srv), instances, nil, Metrics, basic, c, ch), ch))
+
+			var actual []string
+			for _, m := range metrics {
+				if !strings.Contains(m.Name, "_write_latency") && !strings.Contains(m.Name, "_read_latency") {
+					continue
+				}
+				key := m.Name
+				if td.statisticLabel {
+					key += "{" + m.Labels["statistic"] + "}"
+				}
+				actual = append(actual, key)
+				assert.Equal(t, float64(42), m.Value, "%s", m)
+			}
+			assert.ElementsMatch(t, td.expected, actual)
+		})
+	}
+}
+
+func TestScraperCatalog(t *testing.T) {
+	fake := &fakeCloudWatch{
+		pageSize: 100,
+	}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	instances := []sessions.Instance{{
+		Region:   "us-east-1",
+		Instance: "test-0",
+	}}
+	metrics := config.MergeCatalog(Metrics, []config.CatalogMetric{{
+		CWName:         "ReplicationSlotDiskUsage",
+		PrometheusName: "aws_rds_replication_slot_disk_usage_bytes",
+		Scale:          2,
+	}, {
+		CWName:   "CPUUtilization",
+		Disabled: true,
+	}})
+
+	logger := promlog.New(&promlog.Config{})
+	c := &Collector{
+		l: logger,
+	}
+
+	ch := make(chan prometheus.Metric)
+	actual := make(map[string]float64)
+	for _, m := range helpers.ReadMetrics(scrape(NewScraper(newFakeConfig(srv), instances, nil, metrics, config.Basic{}, c, ch), ch)) {
+		actual[m.Name] = m.Value
+	}
+
+	assert.Equal(t, float64(84), actual["aws_rds_replication_slot_disk_usage_bytes"])
+	assert.Equal(t, float64(42), actual["aws_rds_write_iops_average"])
+	assert.NotContains(t, actual, "aws_rds_cpu_utilization_average")
+	assert.NotContains(t, actual, "aws_rds_cluster_cpu_utilization_average")
+}
+
+func TestScraperStatus(t *testing.T) {
+	fake := &fakeCloudWatch{
+		pageSize:  100,
+		forbidden: map[string]string{"test-0": "CPUUtilization"},
+	}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	instances := []sessions.Instance{{
+		Region:   "us-east-1",
+		Instance: "test-0",
+	}, {
+		Region:   "us-east-1",
+		Instance: "test-1",
+	}}
+
+	logger := promlog.New(&promlog.Config{})
+	c := &Collector{
+		l:      logger,
+		status: health.NewTracker("basic"),
+	}
+
+	ch := make(chan prometheus.Metric)
+	s := NewScraper(newFakeConfig(srv), instances, nil, Metrics, config.Basic{}, c, ch)
+	s.status = c.status
+	scrape(s, ch)
+
+	actual := make(map[string]float64)
+	for _, m := range helpers.ReadMetrics(helpers.CollectMetrics(c.status)) {
+		if m.Name == "rds_exporter_last_scrape_success_timestamp_seconds" {
+			m.Value = 1
+		}
+		assert.Equal(t, "basic", m.Labels["collector"])
+		actual[fmt.Sprintf("%s %s %s %s", m.Name, m.Labels["instance"], m.Labels["metric"], m.Labels["code"])] = m.Value
+	}
+	expected := map[string]float64{
+		"rds_exporter_scrape_success test-0  ":                             0,
+		"rds_exporter_scrape_success test-1  ":                             1,
+		"rds_exporter_last_scrape_success_timestamp_seconds test-1  ":      1,
+		"rds_exporter_scrape_errors_total test-0 CPUUtilization Forbidden": 1,
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestProbe(t *testing.T) {
+	fake := &fakeCloudWatch{pageSize: 100}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	logger := promlog.New(&promlog.Config{})
+	c := &Collector{
+		l:       logger,
+		config:  &config.Config{},
+		metrics: Metrics,
+	}
+
+	probe := func(instance sessions.Instance) ([]prometheus.Metric, error) {
+		ch := make(chan prometheus.Metric)
+		var err error
+		go func() {
+			err = c.Probe(context.Background(), newFakeConfig(srv), instance, ch)
+			close(ch)
+		}()
+
+		var metrics []prometheus.Metric
+		for m := range ch {
+			metrics = append(metrics, m)
+		}
+		return metrics, err
+	}
+
+	metrics, err := probe(sessions.Instance{Region: "us-east-1", Instance: "test-0"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, metrics)
+
+	fake.forbidden = map[string]string{"test-1": "CPUUtilization"}
+	metrics, err = probe(sessions.Instance{Region: "us-east-1", Instance: "test-1"})
+	assert.Error(t, err)
+	assert.NotEmpty(t, metrics)
+}
@@ -8,19 +8,47 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// Config contains retry policy and rate limiter settings.
+type Config struct {
+	MaxAttempts    int           // maximal number of attempts per request; 1 disables retries
+	AttemptTimeout time.Duration // timeout of a single attempt until response headers are received; 0 disables it
+	MinBackoff     time.Duration // delay before the first retry, doubled for every next one
+	MaxBackoff     time.Duration // maximal delay between retries
+	RateLimit      float64       // maximal requests per second for each AWS account (see WithLimiterKey) or access key; 0 disables limiter
+	RateBurst      int           // maximal requests burst for each AWS account or access key
+}
+
+// DefaultConfig returns default retry policy and rate limiter settings.
+func DefaultConfig() Config {
+	return Config{
+		MaxAttempts:    3,
+		AttemptTimeout: 15 * time.Second,
+		MinBackoff:     500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		RateLimit:      20,
+		RateBurst:      40,
+	}
+}
+
 // Client represents HTTP client for all AWS APIs with metrics reporting.
 type Client struct {
 	c *http.Client
 	t *transport
 }
 
-// New creates new Client.
+// New creates new Client with default settings.
 func New(logger log.Logger) *Client {
-	t := newTransport(logger)
+	return NewWithConfig(DefaultConfig(), logger)
+}
+
+// NewWithConfig creates new Client with given settings.
+// There is no overall timeout: each attempt is limited by cfg.AttemptTimeout,
+// and the whole request (with retries and limiter waits) by the request context.
+func NewWithConfig(cfg Config, logger log.Logger) *Client {
+	t := newTransport(cfg, logger)
 	return &Client{
 		c: &http.Client{
 			Transport: t,
-			Timeout:   15 * time.Second,
 		},
 		t: t,
 	}
@@ -34,11 +62,19 @@ func (c *Client) HTTP() *http.Client {
 // Describe implements prometheus.Collector.
 func (c *Client) Describe(ch chan<- *prometheus.Desc) {
 	c.t.mRequests.Describe(ch)
+	c.t.mDurations.Describe(ch)
+	c.t.mRetries.Describe(ch)
+	c.t.mThrottled.Describe(ch)
+	c.t.mLimiterWait.Describe(ch)
 }
 
 // Collect implements prometheus.Collector.
 func (c *Client) Collect(ch chan<- prometheus.Metric) {
 	c.t.mRequests.Collect(ch)
+	c.t.mDurations.Collect(ch)
+	c.t.mRetries.Collect(ch)
+	c.t.mThrottled.Collect(ch)
+	c.t.mLimiterWait.Collect(ch)
 }
 
 // check interfaces
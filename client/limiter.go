@@ -0,0 +1,82 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// idleBucketTTL is a time after which unused token buckets are removed
+// (for example, buckets of expired temporary credentials).
+const idleBucketTTL = 10 * time.Minute
+
+// bucket is a token bucket state.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter limits requests rate using a token bucket per key.
+type limiter struct {
+	rate  float64
+	burst float64
+
+	m       sync.Mutex
+	buckets map[string]*bucket
+}
+
+// newLimiter returns new limiter, or nil if rate is not positive.
+func newLimiter(rate float64, burst int) *limiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// reserve takes a token from the bucket for given key and returns a delay
+// after which request may be sent.
+func (l *limiter) reserve(key string, now time.Time) time.Duration {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	b := l.buckets[key]
+	if b == nil {
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > idleBucketTTL {
+				delete(l.buckets, k)
+			}
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * l.rate
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+		b.last = now
+	}
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / l.rate * float64(time.Second))
+}
+
+// cancel returns a token taken by reserve for given key when request was not sent.
+func (l *limiter) cancel(key string) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if b := l.buckets[key]; b != nil && b.tokens < l.burst {
+		b.tokens++
+	}
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	awsMiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+)
+
+// credentialScope returns parts of Signature Version 4 credential scope:
+// access key ID, date, region, service, and terminator; or nil for unsigned requests.
+// Authorization header looks like:
+// AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=..., Signature=...
+func credentialScope(req *http.Request) []string {
+	auth := req.Header.Get("Authorization")
+	i := strings.Index(auth, "Credential=")
+	if i < 0 {
+		return nil
+	}
+	auth = auth[i+len("Credential="):]
+	if i = strings.IndexAny(auth, ", "); i >= 0 {
+		auth = auth[:i]
+	}
+	return strings.Split(auth, "/")
+}
+
+// accessKey returns AWS access key ID used to sign request, or empty string for unsigned requests.
+func accessKey(req *http.Request) string {
+	if scope := credentialScope(req); len(scope) > 0 {
+		return scope[0]
+	}
+	return ""
+}
+
+type limiterKeyCtx struct{}
+
+// WithLimiterKey returns a copy of the context with rate limiter key (typically, AWS account ID)
+// for requests made with it.
+func WithLimiterKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, limiterKeyCtx{}, key)
+}
+
+// limiterKey returns rate limiter key of the request: the one set by WithLimiterKey,
+// or AWS access key ID used to sign it.
+func limiterKey(req *http.Request) string {
+	if key, _ := req.Context().Value(limiterKeyCtx{}).(string); key != "" {
+		return key
+	}
+	return accessKey(req)
+}
+
+// requestLabels returns AWS service, operation, and region of the request.
+// They are taken from AWS SDK middleware metadata in request's context;
+// service and region fall back to the signature credential scope for other requests.
+func requestLabels(req *http.Request) (service, operation, region string) {
+	ctx := req.Context()
+	service = awsMiddleware.GetServiceID(ctx)
+	operation = awsMiddleware.GetOperationName(ctx)
+	region = awsMiddleware.GetRegion(ctx)
+
+	if scope := credentialScope(req); len(scope) == 5 {
+		if region == "" {
+			region = scope[2]
+		}
+		if service == "" {
+			service = scope[3]
+		}
+	}
+	return
+}
@@ -1,60 +1,295 @@
 package client
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"math/rand"
 	"net/http"
+	"regexp"
 	"strconv"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// maxErrorBodySize is the maximal size of error response body read to find error code.
+const maxErrorBodySize = 64 * 1024
+
+// errorCodeRE matches error code in AWS XML (<Code>Throttling</Code>)
+// and JSON ("__type":"com.amazonaws...#ThrottlingException", "code":"...") error responses.
+var errorCodeRE = regexp.MustCompile(`<Code>([^<]+)</Code>|"(?:__type|code)"\s*:\s*"(?:[^"#]*#)?([^"]+)"`)
+
+// Retry reasons.
+const (
+	reasonThrottling  = "throttling"
+	reasonServerError = "server_error"
+	reasonNetwork     = "network"
+)
+
 type transport struct {
-	t *http.Transport
-	l log.Logger
+	t       *http.Transport
+	l       log.Logger
+	cfg     Config
+	limiter *limiter
 
-	mRequests  prometheus.Counter
-	mResponses *prometheus.SummaryVec
+	mRequests    *prometheus.CounterVec
+	mDurations   *prometheus.HistogramVec
+	mRetries     *prometheus.CounterVec
+	mThrottled   *prometheus.CounterVec
+	mLimiterWait prometheus.Counter
 }
 
-func newTransport(logger log.Logger) *transport {
+func newTransport(cfg Config, logger log.Logger) *transport {
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+
 	return &transport{
 		t: &http.Transport{
 			MaxIdleConnsPerHost: 5,
 			IdleConnTimeout:     2 * time.Minute,
 			Proxy:               http.ProxyFromEnvironment,
 		},
-		l: log.With(logger, "component", "transport"),
+		l:       log.With(logger, "component", "transport"),
+		cfg:     cfg,
+		limiter: newLimiter(cfg.RateLimit, cfg.RateBurst),
 
-		mRequests: prometheus.NewCounter(prometheus.CounterOpts{
+		mRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "rds_exporter_requests_total",
 			Help: "Total number of AWS API requests.",
+		}, []string{"service", "operation", "region", "status"}),
+		mDurations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:                        "rds_exporter_request_duration_seconds",
+			Help:                        "AWS API requests latency distributions.",
+			Buckets:                     prometheus.DefBuckets,
+			NativeHistogramBucketFactor: 1.1,
+		}, []string{"service", "operation", "region", "status"}),
+		mRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rds_exporter_request_retries_total",
+			Help: "Total number of retried AWS API requests by reason.",
+		}, []string{"service", "operation", "region", "reason"}),
+		mThrottled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rds_exporter_throttled_requests_total",
+			Help: "Total number of AWS API requests rejected due to throttling.",
+		}, []string{"service", "operation", "region"}),
+		mLimiterWait: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "rds_exporter_rate_limiter_wait_seconds_total",
+			Help: "Total time AWS API requests waited for the client-side rate limiter, in seconds.",
 		}),
-		mResponses: prometheus.NewSummaryVec(prometheus.SummaryOpts{
-			Name: "rds_exporter_responses_durations_seconds",
-			Help: "AWS API responses latency distributions.",
-		}, []string{"status"}),
 	}
 }
 
+// RoundTrip implements http.RoundTripper.
+// It waits for the rate limiter, and retries throttled requests, 5xx responses and network errors
+// with jittered exponential backoff.
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// buffer body to send it again on retry
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx := req.Context()
+	key := limiterKey(req)
+	service, operation, region := requestLabels(req)
+	for attempt := 1; ; attempt++ {
+		if err := t.wait(ctx, key); err != nil {
+			return nil, err
+		}
+
+		// attempt timeout covers only sending request and receiving response headers (and error body),
+		// so long-lived response streams (like StartLiveTail) are not interrupted
+		attemptCtx, cancel := context.WithCancel(ctx)
+		var timer *time.Timer
+		if t.cfg.AttemptTimeout > 0 {
+			timer = time.AfterFunc(t.cfg.AttemptTimeout, cancel)
+		}
+		r := req.Clone(attemptCtx)
+		if body != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(body)), nil
+			}
+		}
+
+		resp, err := t.roundTrip(r, service, operation, region)
+		if resp != nil {
+			// attempt context is canceled when the caller closes the body
+			resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+		} else {
+			cancel()
+		}
+		reason := retryReason(ctx, resp, err)
+		if timer != nil && !timer.Stop() && err != nil {
+			err = fmt.Errorf("attempt timeout %s exceeded: %w", t.cfg.AttemptTimeout, err)
+		}
+		if reason == reasonThrottling {
+			t.mThrottled.WithLabelValues(service, operation, region).Inc()
+		}
+		if reason == "" || attempt >= t.cfg.MaxAttempts {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body) //nolint:errcheck
+			resp.Body.Close()
+		}
+
+		delay := t.backoff(attempt)
+		t.mRetries.WithLabelValues(service, operation, region, reason).Inc()
+		level.Debug(t.l).Log("msg", fmt.Sprintf("%s %s: retrying (%s) in %s.", req.Method, req.URL.String(), reason, delay))
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// wait waits for the rate limiter.
+// The reserved token is returned if context is canceled before the request is sent.
+func (t *transport) wait(ctx context.Context, key string) error {
+	if t.limiter == nil {
+		return ctx.Err()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	delay := t.limiter.reserve(key, time.Now())
+	if delay <= 0 {
+		return nil
+	}
+
+	t.mLimiterWait.Add(delay.Seconds())
+	if err := sleep(ctx, delay); err != nil {
+		t.limiter.cancel(key)
+		return err
+	}
+	return nil
+}
+
+// cancelBody is a response body that cancels attempt context on close.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close implements io.Closer.
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// roundTrip sends a single request and records metrics.
+func (t *transport) roundTrip(req *http.Request, service, operation, region string) (*http.Response, error) {
 	// We could use "net/http/httptrace" package if we ever need more metrics.
 
 	start := time.Now()
-	t.mRequests.Inc()
 	resp, err := t.t.RoundTrip(req)
 	duration := time.Since(start)
+
+	status := "err"
+	if resp != nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	t.mRequests.WithLabelValues(service, operation, region, status).Inc()
+	t.mDurations.WithLabelValues(service, operation, region, status).Observe(duration.Seconds())
+
 	if resp != nil {
-		t.mResponses.WithLabelValues(strconv.Itoa(resp.StatusCode)).Observe(duration.Seconds())
 		level.Debug(t.l).Log("msg", fmt.Sprintf("%s %s -> %d (%s)", req.Method, req.URL.String(), resp.StatusCode, duration))
 	} else {
-		t.mResponses.WithLabelValues("err").Observe(duration.Seconds())
 		level.Error(t.l).Log("msg", fmt.Sprintf("%s %s -> %s (%s)", req.Method, req.URL.String(), err, duration))
 	}
 	return resp, err
 }
 
+// retryReason returns a reason to retry request with given response or error, or empty string.
+// Error response body is read to find error code, and replaced for the caller.
+func retryReason(ctx context.Context, resp *http.Response, err error) string {
+	if err != nil {
+		if ctx.Err() != nil {
+			return ""
+		}
+		return reasonNetwork
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return reasonThrottling
+
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(b), resp.Body), resp.Body}
+
+		if isThrottling(b) {
+			return reasonThrottling
+		}
+		return ""
+
+	case resp.StatusCode >= 500:
+		return reasonServerError
+
+	default:
+		return ""
+	}
+}
+
+// isThrottling returns true if error response body contains throttling error code.
+func isThrottling(body []byte) bool {
+	for _, m := range errorCodeRE.FindAllSubmatch(body, -1) {
+		code := string(m[1])
+		if code == "" {
+			code = string(m[2])
+		}
+		if _, ok := retry.DefaultThrottleErrorCodes[code]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns jittered delay before given retry attempt (starting from 1).
+func (t *transport) backoff(attempt int) time.Duration {
+	d := t.cfg.MinBackoff
+	for i := 1; i < attempt && d < t.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > t.cfg.MaxBackoff {
+		d = t.cfg.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+
+	// "equal jitter": half of the delay is fixed, half is random
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// sleep waits for given duration or context cancellation.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // check interface
 var _ http.RoundTripper = (*transport)(nil)
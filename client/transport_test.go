@@ -0,0 +1,198 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportRetry(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		assert.Equal(t, "Action=GetMetricData", string(b))
+
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			rw.WriteHeader(http.StatusBadRequest)
+			io.WriteString(rw, `<ErrorResponse><Error><Type>Sender</Type><Code>Throttling</Code><Message>Rate exceeded</Message></Error></ErrorResponse>`)
+		case 2:
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			io.WriteString(rw, "OK")
+		}
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.MinBackoff = time.Millisecond
+	c := NewWithConfig(cfg, log.NewNopLogger())
+
+	req, err := http.NewRequest("POST", srv.URL, strings.NewReader("Action=GetMetricData"))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240101/us-east-1/monitoring/aws4_request, SignedHeaders=host, Signature=abc")
+	resp, err := c.HTTP().Do(req)
+	require.NoError(t, err)
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "OK", string(b))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.t.mRequests.WithLabelValues("monitoring", "", "us-east-1", "400")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.t.mRequests.WithLabelValues("monitoring", "", "us-east-1", "503")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.t.mRequests.WithLabelValues("monitoring", "", "us-east-1", "200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.t.mThrottled.WithLabelValues("monitoring", "", "us-east-1")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.t.mRetries.WithLabelValues("monitoring", "", "us-east-1", reasonThrottling)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.t.mRetries.WithLabelValues("monitoring", "", "us-east-1", reasonServerError)))
+}
+
+func TestTransportNoRetry(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		rw.WriteHeader(http.StatusForbidden)
+		io.WriteString(rw, `{"__type":"com.amazon.coral.service#AccessDeniedException","message":"denied"}`)
+	}))
+	defer srv.Close()
+
+	c := NewWithConfig(DefaultConfig(), log.NewNopLogger())
+	resp, err := c.HTTP().Get(srv.URL)
+	require.NoError(t, err)
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Contains(t, string(b), "AccessDeniedException")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestTransportAttemptTimeout(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-req.Context().Done()
+			return
+		}
+		io.WriteString(rw, "OK")
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.AttemptTimeout = 100 * time.Millisecond
+	cfg.MinBackoff = time.Millisecond
+	c := NewWithConfig(cfg, log.NewNopLogger())
+
+	resp, err := c.HTTP().Get(srv.URL)
+	require.NoError(t, err)
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "OK", string(b))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestTransportAttemptTimeoutStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		for i := 0; i < 5; i++ {
+			io.WriteString(rw, "event\n")
+			rw.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.AttemptTimeout = 100 * time.Millisecond
+	c := NewWithConfig(cfg, log.NewNopLogger())
+
+	// streamed body is read longer than attempt timeout
+	resp, err := c.HTTP().Get(srv.URL)
+	require.NoError(t, err)
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("event\n", 5), string(b))
+}
+
+func TestTransportSDKLabels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusForbidden)
+		io.WriteString(rw, `<ErrorResponse><Error><Type>Sender</Type><Code>AccessDenied</Code><Message>denied</Message></Error></ErrorResponse>`)
+	}))
+	defer srv.Close()
+
+	c := NewWithConfig(DefaultConfig(), log.NewNopLogger())
+	svc := rds.NewFromConfig(aws.Config{
+		Region:           "eu-west-1",
+		HTTPClient:       c.HTTP(),
+		Credentials:      credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", ""),
+		BaseEndpoint:     aws.String(srv.URL),
+		RetryMaxAttempts: 1,
+	})
+	_, err := svc.DescribeDBInstances(context.Background(), &rds.DescribeDBInstancesInput{})
+	require.Error(t, err)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.t.mRequests.WithLabelValues("RDS", "DescribeDBInstances", "eu-west-1", "403")))
+	assert.Equal(t, 1, testutil.CollectAndCount(c.t.mDurations))
+}
+
+func TestIsThrottling(t *testing.T) {
+	assert.True(t, isThrottling([]byte(`<Response><Errors><Error><Code>RequestLimitExceeded</Code></Error></Errors></Response>`)))
+	assert.True(t, isThrottling([]byte(`{"__type":"com.amazonaws.logs#ThrottlingException","message":"Rate exceeded"}`)))
+	assert.False(t, isThrottling([]byte(`<ErrorResponse><Error><Code>AccessDenied</Code></Error></ErrorResponse>`)))
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(2, 2)
+	now := time.Now()
+	assert.Zero(t, l.reserve("AKID", now))
+	assert.Zero(t, l.reserve("AKID", now))
+	assert.Equal(t, 500*time.Millisecond, l.reserve("AKID", now))
+	assert.Zero(t, l.reserve("OTHER", now))
+
+	// removes idle buckets
+	assert.Zero(t, l.reserve("NEW", now.Add(idleBucketTTL+time.Minute)))
+	assert.Len(t, l.buckets, 1)
+}
+
+func TestLimiterCancel(t *testing.T) {
+	tr := newTransport(Config{RateLimit: 1, RateBurst: 1}, log.NewNopLogger())
+	require.NoError(t, tr.wait(context.Background(), "AKID"))
+
+	// token is not taken for canceled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Error(t, tr.wait(ctx, "AKID"))
+	assert.InDelta(t, 0, tr.limiter.buckets["AKID"].tokens, 0.1)
+
+	// token is returned when context is canceled while waiting
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.Error(t, tr.wait(ctx, "AKID"))
+	assert.InDelta(t, 0, tr.limiter.buckets["AKID"].tokens, 0.1)
+}
+
+func TestAccessKey(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	assert.Equal(t, "", accessKey(req))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=host, Signature=abc")
+	assert.Equal(t, "AKIDEXAMPLE", accessKey(req))
+	assert.Equal(t, "AKIDEXAMPLE", limiterKey(req))
+
+	req = req.WithContext(WithLimiterKey(req.Context(), "account/123456789012"))
+	assert.Equal(t, "account/123456789012", limiterKey(req))
+}
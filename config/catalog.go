@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// DefaultNamespace is used for catalog metrics without namespace.
+const DefaultNamespace = "AWS/RDS"
+
+// Supported catalog metric dimensions.
+const (
+	DimensionInstance = "DBInstanceIdentifier"
+	DimensionCluster  = "DBClusterIdentifier"
+	DimensionRole     = "Role"
+)
+
+// Supported catalog metric value transforms.
+const (
+	TransformBootTime = "boot_time" // uptime in seconds -> boot UNIX timestamp
+)
+
+// CatalogMetric describes a single CloudWatch metric and its Prometheus representation.
+type CatalogMetric struct {
+	CWName         string   `yaml:"cw_name"`
+	Namespace      string   `yaml:"namespace"`  // may be empty for AWS/RDS
+	Dimensions     []string `yaml:"dimensions"` // may be empty for DBInstanceIdentifier
+	PrometheusName string   `yaml:"prometheus_name"`
+	PrometheusHelp string   `yaml:"prometheus_help"` // may be empty
+	Statistics     []string `yaml:"statistics"`      // may be empty
+	Scale          float64  `yaml:"scale"`           // may be zero for 1
+	Transform      string   `yaml:"transform"`       // may be empty
+	Disabled       bool     `yaml:"disabled"`
+}
+
+// GetNamespace returns metric's CloudWatch namespace.
+func (m CatalogMetric) GetNamespace() string {
+	if m.Namespace == "" {
+		return DefaultNamespace
+	}
+	return m.Namespace
+}
+
+// Key returns a string that uniquely identifies CloudWatch metric: namespace, name, and dimensions.
+func (m CatalogMetric) Key() string {
+	dimensions := m.Dimensions
+	if len(dimensions) == 0 {
+		dimensions = []string{DimensionInstance}
+	}
+	return m.GetNamespace() + "/" + m.CWName + "/" + strings.Join(dimensions, ",")
+}
+
+// Validate checks that metric can be queried and exported.
+func (m CatalogMetric) Validate() error {
+	if m.CWName == "" {
+		return fmt.Errorf("cw_name is empty")
+	}
+
+	switch strings.Join(m.Dimensions, ",") {
+	case "", DimensionInstance, DimensionCluster, DimensionCluster + "," + DimensionRole:
+	default:
+		return fmt.Errorf("%s: unsupported dimensions %v", m.CWName, m.Dimensions)
+	}
+
+	if m.Disabled {
+		return nil
+	}
+
+	if !model.IsValidMetricName(model.LabelValue(m.PrometheusName)) {
+		return fmt.Errorf("%s: invalid prometheus_name %q", m.CWName, m.PrometheusName)
+	}
+	if err := validateStatistics(m.Statistics); err != nil {
+		return fmt.Errorf("%s: %w", m.CWName, err)
+	}
+	if m.Scale < 0 {
+		return fmt.Errorf("%s: negative scale", m.CWName)
+	}
+	switch m.Transform {
+	case "", TransformBootTime:
+	default:
+		return fmt.Errorf("%s: unexpected transform %q", m.CWName, m.Transform)
+	}
+	return nil
+}
+
+// MergeCatalog returns catalog with given overrides applied:
+// metrics with the same key are replaced or removed (if disabled), new metrics are appended.
+func MergeCatalog(catalog, overrides []CatalogMetric) []CatalogMetric {
+	res := make([]CatalogMetric, 0, len(catalog)+len(overrides))
+	indexes := make(map[string]int, len(catalog)) // key -> index in res
+	for _, m := range catalog {
+		indexes[m.Key()] = len(res)
+		res = append(res, m)
+	}
+
+	for _, m := range overrides {
+		if i, ok := indexes[m.Key()]; ok {
+			res[i] = m
+			continue
+		}
+		indexes[m.Key()] = len(res)
+		res = append(res, m)
+	}
+
+	enabled := res[:0]
+	for _, m := range res {
+		if !m.Disabled {
+			enabled = append(enabled, m)
+		}
+	}
+	return enabled
+}
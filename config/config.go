@@ -1,7 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"regexp"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -16,6 +19,145 @@ import (
 // 	MySQL       InstanceType = "mysql"
 // )
 
+// Timing contains CloudWatch GetMetricData query parameters for basic metrics.
+// Zero values are inherited from the upper level.
+type Timing struct {
+	Period time.Duration `yaml:"period"` // datapoint granularity
+	Delay  time.Duration `yaml:"delay"`  // how far the query end time is in the past
+	Range  time.Duration `yaml:"range"`  // how long the query time range is
+}
+
+// Merge returns timing with zero values replaced by defaults.
+func (t Timing) Merge(defaults Timing) Timing {
+	if t.Period == 0 {
+		t.Period = defaults.Period
+	}
+	if t.Delay == 0 {
+		t.Delay = defaults.Delay
+	}
+	if t.Range == 0 {
+		t.Range = defaults.Range
+	}
+	return t
+}
+
+// Validate checks that non-zero values are accepted by CloudWatch.
+func (t Timing) Validate() error {
+	switch {
+	case t.Period < 0 || t.Delay < 0 || t.Range < 0:
+		return fmt.Errorf("period, delay and range must not be negative")
+	case t.Period%time.Second != 0 || t.Delay%time.Second != 0 || t.Range%time.Second != 0:
+		return fmt.Errorf("period, delay and range must be whole seconds")
+	}
+
+	// https://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/API_MetricStat.html
+	switch t.Period {
+	case 0, time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second:
+	default:
+		if t.Period%time.Minute != 0 {
+			return fmt.Errorf("period must be 1, 5, 10, 30 seconds or a multiple of 60 seconds, got %s", t.Period)
+		}
+	}
+
+	if t.Period != 0 && t.Range != 0 && t.Range < t.Period {
+		return fmt.Errorf("range %s is less than period %s", t.Range, t.Period)
+	}
+	return nil
+}
+
+// Standard CloudWatch statistics.
+var standardStatistics = map[string]bool{
+	"Average":     true,
+	"Maximum":     true,
+	"Minimum":     true,
+	"Sum":         true,
+	"SampleCount": true,
+}
+
+// extendedStatisticRE matches CloudWatch extended statistics like p99, p99.9, tm90, TM(10%:90%) or PR(:300).
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/Statistics-definitions.html
+var extendedStatisticRE = regexp.MustCompile(`^((p|tm|wm|tc|ts)(100|\d{1,2}(\.\d+)?)|(TM|WM|TC|TS|PR)\([^():]*:[^():]*\))$`)
+
+// ValidateStatistic checks that given statistic is a standard or extended CloudWatch statistic.
+func ValidateStatistic(statistic string) error {
+	if standardStatistics[statistic] || extendedStatisticRE.MatchString(statistic) {
+		return nil
+	}
+	return fmt.Errorf("unexpected statistic %q", statistic)
+}
+
+// BasicMetric contains configuration of a single basic metric.
+type BasicMetric struct {
+	Timing     `yaml:",inline"`
+	Statistics []string `yaml:"statistics"` // may be empty
+}
+
+// Basic contains basic metrics configuration.
+type Basic struct {
+	Timing         `yaml:",inline"`
+	Statistics     []string               `yaml:"statistics"`      // may be empty
+	Metrics        map[string]BasicMetric `yaml:"metrics"`         // CloudWatch metric name -> configuration, may be empty
+	StatisticLabel bool                   `yaml:"statistic_label"` // global only
+}
+
+// Resolve returns timing for given CloudWatch metric name:
+// metric-specific values take precedence over values for all metrics, then over given defaults.
+func (b Basic) Resolve(metric string, defaults Timing) Timing {
+	return b.Metrics[metric].Merge(b.Timing.Merge(defaults))
+}
+
+// ResolveStatistics returns statistics for given CloudWatch metric name with the same precedence as Resolve.
+func (b Basic) ResolveStatistics(metric string, defaults []string) []string {
+	if s := b.Metrics[metric].Statistics; len(s) != 0 {
+		return s
+	}
+	if len(b.Statistics) != 0 {
+		return b.Statistics
+	}
+	return defaults
+}
+
+// Validate checks timing and statistics for all metrics and for each metric.
+func (b Basic) Validate() error {
+	if err := b.Timing.Validate(); err != nil {
+		return err
+	}
+	if err := validateStatistics(b.Statistics); err != nil {
+		return err
+	}
+	for metric, m := range b.Metrics {
+		if err := m.Timing.Validate(); err != nil {
+			return fmt.Errorf("metric %s: %w", metric, err)
+		}
+		if err := validateStatistics(m.Statistics); err != nil {
+			return fmt.Errorf("metric %s: %w", metric, err)
+		}
+	}
+	return nil
+}
+
+// validateLocal checks instance, cluster, or discovery job level configuration.
+func (b Basic) validateLocal() error {
+	if b.StatisticLabel {
+		return fmt.Errorf("statistic_label can be set only globally")
+	}
+	return b.Validate()
+}
+
+func validateStatistics(statistics []string) error {
+	seen := make(map[string]bool, len(statistics))
+	for _, statistic := range statistics {
+		if err := ValidateStatistic(statistic); err != nil {
+			return err
+		}
+		if seen[statistic] {
+			return fmt.Errorf("duplicate statistic %q", statistic)
+		}
+		seen[statistic] = true
+	}
+	return nil
+}
+
 // Instance represents a single RDS information from configuration file.
 type Instance struct {
 	Region                 string            `yaml:"region"`
@@ -25,8 +167,10 @@ type Instance struct {
 	AWSRoleArn             string            `yaml:"aws_role_arn"`   // may be empty
 	DisableBasicMetrics    bool              `yaml:"disable_basic_metrics"`
 	DisableEnhancedMetrics bool              `yaml:"disable_enhanced_metrics"`
-	Labels                 map[string]string `yaml:"labels"` // may be empty
+	EnhancedInterval       time.Duration     `yaml:"enhanced_interval"` // overrides enhanced monitoring interval, may be zero
+	Labels                 map[string]string `yaml:"labels"`            // may be empty
 	IRSAEnabled            bool              `yaml:"irsa_enabled"`
+	Basic                  Basic             `yaml:"basic"` // may be empty
 
 	// TODO Type InstanceType `yaml:"type"` // may be empty for old pmm-managed
 }
@@ -40,9 +184,165 @@ func (i Instance) String() string {
 	return res
 }
 
+// Cluster represents a single Aurora cluster information from configuration file.
+type Cluster struct {
+	Region       string            `yaml:"region"`
+	Cluster      string            `yaml:"cluster"`
+	AWSAccessKey string            `yaml:"aws_access_key"` // may be empty
+	AWSSecretKey string            `yaml:"aws_secret_key"` // may be empty
+	AWSRoleArn   string            `yaml:"aws_role_arn"`   // may be empty
+	Labels       map[string]string `yaml:"labels"`         // may be empty
+	IRSAEnabled  bool              `yaml:"irsa_enabled"`
+	Basic        Basic             `yaml:"basic"` // may be empty
+}
+
+func (c Cluster) String() string {
+	res := c.Region + "/" + c.Cluster
+	if c.AWSAccessKey != "" {
+		res += " (" + c.AWSAccessKey + ")"
+	}
+
+	return res
+}
+
+// Discovery job types.
+const (
+	DiscoveryTypeInstance = "instance"
+	DiscoveryTypeCluster  = "cluster"
+)
+
+// DiscoveryJob represents a single RDS instances discovery rule from configuration file.
+type DiscoveryJob struct {
+	Type                   string            `yaml:"type"` // instance (default) or cluster
+	Regions                []string          `yaml:"regions"`
+	AWSAccessKey           string            `yaml:"aws_access_key"` // may be empty
+	AWSSecretKey           string            `yaml:"aws_secret_key"` // may be empty
+	AWSRoleArn             string            `yaml:"aws_role_arn"`   // may be empty
+	IRSAEnabled            bool              `yaml:"irsa_enabled"`
+	Engines                []string          `yaml:"engines"`    // may be empty
+	Names                  []string          `yaml:"names"`      // regular expressions, may be empty
+	Tags                   map[string]string `yaml:"tags"`       // tag name -> value regular expression, may be empty
+	TagLabels              map[string]string `yaml:"tag_labels"` // tag name -> label name, may be empty
+	DisableBasicMetrics    bool              `yaml:"disable_basic_metrics"`
+	DisableEnhancedMetrics bool              `yaml:"disable_enhanced_metrics"`
+	EnhancedInterval       time.Duration     `yaml:"enhanced_interval"` // overrides enhanced monitoring interval, may be zero
+	Labels                 map[string]string `yaml:"labels"`            // may be empty
+	Basic                  Basic             `yaml:"basic"`             // may be empty
+}
+
+// Instance returns Instance for given region and instance name with job's credentials, settings and labels.
+func (j DiscoveryJob) Instance(region, instance string) Instance {
+	labels := make(map[string]string, len(j.Labels))
+	for n, v := range j.Labels {
+		labels[n] = v
+	}
+
+	return Instance{
+		Region:                 region,
+		Instance:               instance,
+		AWSAccessKey:           j.AWSAccessKey,
+		AWSSecretKey:           j.AWSSecretKey,
+		AWSRoleArn:             j.AWSRoleArn,
+		DisableBasicMetrics:    j.DisableBasicMetrics,
+		DisableEnhancedMetrics: j.DisableEnhancedMetrics,
+		EnhancedInterval:       j.EnhancedInterval,
+		Labels:                 labels,
+		IRSAEnabled:            j.IRSAEnabled,
+		Basic:                  j.Basic,
+	}
+}
+
+// Cluster returns Cluster for given region and cluster name with job's credentials and labels.
+func (j DiscoveryJob) Cluster(region, cluster string) Cluster {
+	labels := make(map[string]string, len(j.Labels))
+	for n, v := range j.Labels {
+		labels[n] = v
+	}
+
+	return Cluster{
+		Region:       region,
+		Cluster:      cluster,
+		AWSAccessKey: j.AWSAccessKey,
+		AWSSecretKey: j.AWSSecretKey,
+		AWSRoleArn:   j.AWSRoleArn,
+		Labels:       labels,
+		IRSAEnabled:  j.IRSAEnabled,
+		Basic:        j.Basic,
+	}
+}
+
+// Discovery contains automatic RDS instances discovery configuration.
+type Discovery struct {
+	RefreshInterval time.Duration  `yaml:"refresh_interval"` // may be zero
+	Jobs            []DiscoveryJob `yaml:"jobs"`
+}
+
+// AuthModule contains credentials for /probe endpoint targets.
+type AuthModule struct {
+	AWSAccessKey string `yaml:"aws_access_key"` // may be empty
+	AWSSecretKey string `yaml:"aws_secret_key"` // may be empty
+	AWSRoleArn   string `yaml:"aws_role_arn"`   // may be empty
+	IRSAEnabled  bool   `yaml:"irsa_enabled"`
+}
+
+// Instance returns Instance for given region and instance name with module's credentials.
+func (m AuthModule) Instance(region, instance string) Instance {
+	return Instance{
+		Region:       region,
+		Instance:     instance,
+		AWSAccessKey: m.AWSAccessKey,
+		AWSSecretKey: m.AWSSecretKey,
+		AWSRoleArn:   m.AWSRoleArn,
+		IRSAEnabled:  m.IRSAEnabled,
+	}
+}
+
 // Config contains configuration file information.
 type Config struct {
-	Instances []Instance `yaml:"instances"`
+	Instances   []Instance            `yaml:"instances"`
+	Clusters    []Cluster             `yaml:"clusters"`
+	Discovery   Discovery             `yaml:"discovery"`
+	Basic       Basic                 `yaml:"basic"`
+	Enhanced    Enhanced              `yaml:"enhanced"`
+	Catalog     []CatalogMetric       `yaml:"catalog"`      // overrides for the default basic metrics catalog, may be empty
+	AuthModules map[string]AuthModule `yaml:"auth_modules"` // module name -> credentials, may be empty
+}
+
+// Validate checks basic metrics configuration on all levels, basic metrics catalog, and enhanced metrics settings.
+func (c *Config) Validate() error {
+	if err := c.Basic.Validate(); err != nil {
+		return fmt.Errorf("basic: %w", err)
+	}
+	if err := c.Enhanced.Validate(); err != nil {
+		return fmt.Errorf("enhanced: %w", err)
+	}
+	for _, m := range c.Catalog {
+		if err := m.Validate(); err != nil {
+			return fmt.Errorf("catalog: %w", err)
+		}
+	}
+	for _, instance := range c.Instances {
+		if err := instance.Basic.validateLocal(); err != nil {
+			return fmt.Errorf("instance %s: basic: %w", instance, err)
+		}
+		if instance.EnhancedInterval < 0 {
+			return fmt.Errorf("instance %s: negative enhanced_interval", instance)
+		}
+	}
+	for _, cluster := range c.Clusters {
+		if err := cluster.Basic.validateLocal(); err != nil {
+			return fmt.Errorf("cluster %s: basic: %w", cluster, err)
+		}
+	}
+	for i, job := range c.Discovery.Jobs {
+		if err := job.Basic.validateLocal(); err != nil {
+			return fmt.Errorf("discovery job %d: basic: %w", i, err)
+		}
+		if job.EnhancedInterval < 0 {
+			return fmt.Errorf("discovery job %d: negative enhanced_interval", i)
+		}
+	}
+	return nil
 }
 
 // Load loads configuration from file.
@@ -56,6 +356,9 @@ func Load(filename string) (*Config, error) {
 	if err = yaml.Unmarshal(b, &config); err != nil {
 		return nil, err
 	}
+	if err = config.Validate(); err != nil {
+		return nil, err
+	}
 
 	return &config, nil
 }
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimingValidate(t *testing.T) {
+	for _, timing := range []Timing{
+		{},
+		{Period: time.Second},
+		{Period: 30 * time.Second, Range: time.Minute},
+		{Period: 5 * time.Minute, Delay: 0, Range: 15 * time.Minute},
+	} {
+		assert.NoError(t, timing.Validate(), "%+v", timing)
+	}
+
+	for _, timing := range []Timing{
+		{Period: 2 * time.Second},
+		{Period: 90 * time.Second},
+		{Period: 1500 * time.Millisecond},
+		{Delay: -time.Minute},
+		{Period: 5 * time.Minute, Range: time.Minute},
+	} {
+		assert.Error(t, timing.Validate(), "%+v", timing)
+	}
+}
+
+func TestBasicResolve(t *testing.T) {
+	defaults := Timing{Period: time.Minute, Delay: 10 * time.Minute, Range: 10 * time.Minute}
+	b := Basic{
+		Timing: Timing{Delay: 2 * time.Minute},
+		Metrics: map[string]BasicMetric{
+			"CPUCreditUsage": {Timing: Timing{Period: 5 * time.Minute, Range: 15 * time.Minute}},
+		},
+	}
+
+	assert.Equal(t, Timing{Period: time.Minute, Delay: 2 * time.Minute, Range: 10 * time.Minute}, b.Resolve("CPUUtilization", defaults))
+	assert.Equal(t, Timing{Period: 5 * time.Minute, Delay: 2 * time.Minute, Range: 15 * time.Minute}, b.Resolve("CPUCreditUsage", defaults))
+}
+
+func TestValidateStatistic(t *testing.T) {
+	for _, statistic := range []string{"Average", "SampleCount", "p99", "p99.9", "p100", "tm90", "TM(10%:90%)", "PR(:300)"} {
+		assert.NoError(t, ValidateStatistic(statistic), statistic)
+	}
+	for _, statistic := range []string{"", "average", "Max", "p101", "p", "TM(10%)"} {
+		assert.Error(t, ValidateStatistic(statistic), statistic)
+	}
+}
+
+func TestBasicResolveStatistics(t *testing.T) {
+	defaults := []string{"Average"}
+	b := Basic{
+		Statistics: []string{"Average", "Maximum"},
+		Metrics: map[string]BasicMetric{
+			"WriteLatency": {Statistics: []string{"p99"}},
+		},
+	}
+
+	assert.Equal(t, []string{"Average", "Maximum"}, b.ResolveStatistics("CPUUtilization", defaults))
+	assert.Equal(t, []string{"p99"}, b.ResolveStatistics("WriteLatency", defaults))
+	assert.Equal(t, defaults, Basic{}.ResolveStatistics("WriteLatency", defaults))
+	assert.Error(t, Basic{Statistics: []string{"p99", "p99"}}.Validate())
+}
+
+func TestCatalog(t *testing.T) {
+	catalog := []CatalogMetric{
+		{CWName: "CPUUtilization", PrometheusName: "aws_rds_cpu_utilization_average"},
+		{CWName: "CPUUtilization", Dimensions: []string{DimensionCluster, DimensionRole}, PrometheusName: "aws_rds_cluster_cpu_utilization_average"},
+		{CWName: "FreeableMemory", PrometheusName: "aws_rds_freeable_memory_average"},
+	}
+	overrides := []CatalogMetric{
+		{CWName: "CPUUtilization", Dimensions: []string{DimensionInstance}, PrometheusName: "aws_rds_cpu_utilization_percent"},
+		{CWName: "FreeableMemory", Disabled: true},
+		{CWName: "OldestReplicationSlotLag", PrometheusName: "aws_rds_oldest_replication_slot_lag_bytes"},
+	}
+	for _, m := range append(catalog, overrides...) {
+		assert.NoError(t, m.Validate(), "%+v", m)
+	}
+
+	expected := []CatalogMetric{
+		{CWName: "CPUUtilization", Dimensions: []string{DimensionInstance}, PrometheusName: "aws_rds_cpu_utilization_percent"},
+		{CWName: "CPUUtilization", Dimensions: []string{DimensionCluster, DimensionRole}, PrometheusName: "aws_rds_cluster_cpu_utilization_average"},
+		{CWName: "OldestReplicationSlotLag", PrometheusName: "aws_rds_oldest_replication_slot_lag_bytes"},
+	}
+	assert.Equal(t, expected, MergeCatalog(catalog, overrides))
+
+	for _, m := range []CatalogMetric{
+		{PrometheusName: "aws_rds_cpu_utilization_average"},
+		{CWName: "CPUUtilization"},
+		{CWName: "CPUUtilization", PrometheusName: "aws-rds"},
+		{CWName: "CPUUtilization", PrometheusName: "aws_rds", Dimensions: []string{DimensionRole}},
+		{CWName: "CPUUtilization", PrometheusName: "aws_rds", Transform: "foo"},
+		{CWName: "CPUUtilization", PrometheusName: "aws_rds", Statistics: []string{"p999"}},
+	} {
+		assert.Error(t, m.Validate(), "%+v", m)
+	}
+}
+
+func TestEnhancedValidate(t *testing.T) {
+	assert.NoError(t, Enhanced{}.Validate())
+	assert.NoError(t, Enhanced{ProcessList: ProcessList{Mode: ProcessListAggregate, Limit: 10}}.Validate())
+	assert.Error(t, Enhanced{ProcessList: ProcessList{Mode: "top"}}.Validate())
+	assert.Error(t, Enhanced{ProcessList: ProcessList{Limit: -1}}.Validate())
+	assert.NoError(t, Enhanced{Timestamps: TimestampsBuffer, BufferSize: 60}.Validate())
+	assert.Error(t, Enhanced{Timestamps: "all"}.Validate())
+	assert.Error(t, Enhanced{BufferSize: -1}.Validate())
+	assert.NoError(t, Enhanced{Ingestion: IngestionLiveTail}.Validate())
+	assert.Error(t, Enhanced{Ingestion: "stream"}.Validate())
+	assert.Error(t, Enhanced{Ingestion: IngestionFirehose}.Validate())
+	assert.NoError(t, Enhanced{Ingestion: IngestionFirehose, FirehoseAccessKey: "secret"}.Validate())
+
+	assert.Equal(t, DefaultStalenessTTL, Enhanced{}.GetStalenessTTL())
+	assert.Error(t, Enhanced{StalenessTTL: -time.Minute}.Validate())
+
+	assert.Equal(t, DefaultLogGroup, Enhanced{}.GetLogGroup())
+	assert.Equal(t, "custom", Enhanced{LogGroup: "custom"}.GetLogGroup())
+	assert.Error(t, (&Config{Instances: []Instance{{EnhancedInterval: -time.Second}}}).Validate())
+}
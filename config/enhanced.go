@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Enhanced metrics process list modes.
+const (
+	ProcessListFull      = "full"      // series for each process, with process IDs in labels
+	ProcessListAggregate = "aggregate" // series for each process name, summed
+	ProcessListDrop      = "drop"      // no series
+)
+
+// Enhanced metrics timestamps modes.
+const (
+	TimestampsScrape = "scrape" // the latest event metrics without timestamps, Prometheus uses scrape time
+	TimestampsEvent  = "event"  // the latest event metrics with event timestamp
+	TimestampsBuffer = "buffer" // as event, plus the latest buffer_size events are available separately
+)
+
+// Enhanced metrics ingestion modes.
+const (
+	IngestionPoll     = "poll"      // FilterLogEvents requests
+	IngestionLiveTail = "live_tail" // StartLiveTail sessions, with FilterLogEvents fallback
+	IngestionFirehose = "firehose"  // events delivered by Kinesis Data Firehose to HTTP endpoint
+)
+
+// DefaultLogGroup is the default CloudWatch Logs log group of enhanced monitoring events.
+const DefaultLogGroup = "RDSOSMetrics"
+
+// DefaultStalenessTTL is the default age of the latest event after which instance metrics are dropped.
+const DefaultStalenessTTL = 10 * time.Minute
+
+// DefaultBufferSize is the default maximal number of buffered events per instance.
+const DefaultBufferSize = 600
+
+// ProcessList contains enhanced metrics processList section settings.
+type ProcessList struct {
+	Mode  string `yaml:"mode"`  // full by default
+	Limit int    `yaml:"limit"` // keep only top N processes (or names) by CPU usage; 0 keeps all
+}
+
+// GetMode returns process list mode.
+func (p ProcessList) GetMode() string {
+	if p.Mode == "" {
+		return ProcessListFull
+	}
+	return p.Mode
+}
+
+// Enhanced contains enhanced metrics settings.
+type Enhanced struct {
+	ProcessList ProcessList `yaml:"process_list"`
+	Timestamps  string      `yaml:"timestamps"`  // scrape by default
+	BufferSize  int         `yaml:"buffer_size"` // maximal number of buffered events per instance; DefaultBufferSize by default
+	Ingestion   string      `yaml:"ingestion"`   // poll by default
+	LogGroup    string      `yaml:"log_group"`   // DefaultLogGroup by default
+
+	StalenessTTL time.Duration `yaml:"staleness_ttl"` // DefaultStalenessTTL by default
+
+	FirehoseAccessKey string `yaml:"firehose_access_key"` // expected X-Amz-Firehose-Access-Key header value; required for firehose ingestion
+}
+
+// GetIngestion returns ingestion mode.
+func (e Enhanced) GetIngestion() string {
+	if e.Ingestion == "" {
+		return IngestionPoll
+	}
+	return e.Ingestion
+}
+
+// GetLogGroup returns CloudWatch Logs log group name.
+func (e Enhanced) GetLogGroup() string {
+	if e.LogGroup == "" {
+		return DefaultLogGroup
+	}
+	return e.LogGroup
+}
+
+// GetStalenessTTL returns the age of the latest event after which instance metrics are dropped.
+func (e Enhanced) GetStalenessTTL() time.Duration {
+	if e.StalenessTTL == 0 {
+		return DefaultStalenessTTL
+	}
+	return e.StalenessTTL
+}
+
+// GetTimestamps returns timestamps mode.
+func (e Enhanced) GetTimestamps() string {
+	if e.Timestamps == "" {
+		return TimestampsScrape
+	}
+	return e.Timestamps
+}
+
+// GetBufferSize returns maximal number of buffered events per instance.
+func (e Enhanced) GetBufferSize() int {
+	if e.BufferSize == 0 {
+		return DefaultBufferSize
+	}
+	return e.BufferSize
+}
+
+// Validate checks enhanced metrics settings.
+func (e Enhanced) Validate() error {
+	switch e.ProcessList.GetMode() {
+	case ProcessListFull, ProcessListAggregate, ProcessListDrop:
+	default:
+		return fmt.Errorf("process_list: unexpected mode %q", e.ProcessList.Mode)
+	}
+	if e.ProcessList.Limit < 0 {
+		return fmt.Errorf("process_list: negative limit")
+	}
+	switch e.GetTimestamps() {
+	case TimestampsScrape, TimestampsEvent, TimestampsBuffer:
+	default:
+		return fmt.Errorf("unexpected timestamps mode %q", e.Timestamps)
+	}
+	if e.BufferSize < 0 {
+		return fmt.Errorf("negative buffer_size")
+	}
+	if e.StalenessTTL < 0 {
+		return fmt.Errorf("negative staleness_ttl")
+	}
+	switch e.GetIngestion() {
+	case IngestionPoll, IngestionLiveTail, IngestionFirehose:
+	default:
+		return fmt.Errorf("unexpected ingestion mode %q", e.Ingestion)
+	}
+	if e.GetIngestion() == IngestionFirehose && e.FirehoseAccessKey == "" {
+		return fmt.Errorf("firehose_access_key is required for firehose ingestion")
+	}
+	return nil
+}
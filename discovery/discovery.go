@@ -0,0 +1,377 @@
+// Package discovery finds RDS instances and Aurora clusters by region, engine, name and tags.
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/common/model"
+
+	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/sessions"
+)
+
+// DefaultRefreshInterval is used when discovery refresh interval is not set in configuration file.
+const DefaultRefreshInterval = 5 * time.Minute
+
+// job is a discovery job with compiled regular expressions.
+type job struct {
+	config.DiscoveryJob
+	names []*regexp.Regexp
+	tags  map[string]*regexp.Regexp
+}
+
+// match returns true if given instance or cluster name and tags match job's names and tags.
+// Engines are already filtered by RDS API.
+func (j *job) match(name string, tagList []types.Tag) bool {
+	if len(j.names) > 0 {
+		var found bool
+		for _, re := range j.names {
+			if re.MatchString(name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for key, re := range j.tags {
+		var found bool
+		for _, tag := range tagList {
+			if aws.ToString(tag.Key) == key && re.MatchString(aws.ToString(tag.Value)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// labels adds labels for job's tag labels to given labels.
+func (j *job) labels(labels map[string]string, tagList []types.Tag) {
+	for _, tag := range tagList {
+		if label, ok := j.TagLabels[aws.ToString(tag.Key)]; ok {
+			labels[label] = aws.ToString(tag.Value)
+		}
+	}
+}
+
+// Targets contains discovered or configured instances and clusters.
+type Targets struct {
+	Instances []config.Instance
+	Clusters  []config.Cluster
+}
+
+// Discoverer finds RDS instances matching configured discovery jobs.
+type Discoverer struct {
+	jobs   []job
+	client *http.Client
+	trace  bool
+	logger log.Logger
+
+	last map[string]Targets // the last successful results by job index and region
+}
+
+// New creates a new Discoverer for given configuration.
+func New(cfg config.Discovery, client *http.Client, logger log.Logger, trace bool) (*Discoverer, error) {
+	jobs := make([]job, 0, len(cfg.Jobs))
+	for i, j := range cfg.Jobs {
+		switch j.Type {
+		case "":
+			j.Type = config.DiscoveryTypeInstance
+		case config.DiscoveryTypeInstance, config.DiscoveryTypeCluster:
+			// nothing
+		default:
+			return nil, fmt.Errorf("discovery job %d: unexpected type %q", i, j.Type)
+		}
+
+		if len(j.Regions) == 0 {
+			return nil, fmt.Errorf("discovery job %d: no regions", i)
+		}
+
+		names := make([]*regexp.Regexp, 0, len(j.Names))
+		for _, name := range j.Names {
+			re, err := regexp.Compile(name)
+			if err != nil {
+				return nil, fmt.Errorf("discovery job %d: invalid name regular expression: %w", i, err)
+			}
+			names = append(names, re)
+		}
+
+		tags := make(map[string]*regexp.Regexp, len(j.Tags))
+		for key, value := range j.Tags {
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, fmt.Errorf("discovery job %d: invalid tag %q regular expression: %w", i, key, err)
+			}
+			tags[key] = re
+		}
+
+		for tag, label := range j.TagLabels {
+			if !model.LabelName(label).IsValid() {
+				return nil, fmt.Errorf("discovery job %d: invalid label name %q for tag %q", i, label, tag)
+			}
+		}
+
+		jobs = append(jobs, job{
+			DiscoveryJob: j,
+			names:        names,
+			tags:         tags,
+		})
+	}
+
+	return &Discoverer{
+		jobs:   jobs,
+		client: client,
+		trace:  trace,
+		logger: log.With(logger, "component", "discovery"),
+		last:   make(map[string]Targets),
+	}, nil
+}
+
+// Discover returns all instances and clusters matching discovery jobs, sorted by region and name.
+// Jobs failed in some regions are logged, and their last successful results are used instead,
+// so temporary errors don't remove targets; error is returned only if all of them failed.
+// It should not be called concurrently.
+func (d *Discoverer) Discover(ctx context.Context) (Targets, error) {
+	seenInstances := make(map[string]struct{})
+	seenClusters := make(map[string]struct{})
+	var res Targets
+	var attempts int
+	var errs []error
+	for i, j := range d.jobs {
+		j := j
+		for _, region := range j.Regions {
+			attempts++
+			var err error
+			var t Targets
+			switch j.Type {
+			case config.DiscoveryTypeCluster:
+				t.Clusters, err = d.discoverClusters(ctx, &j, region)
+			default:
+				t.Instances, err = d.discoverInstances(ctx, &j, region)
+			}
+			lastKey := fmt.Sprintf("%d/%s", i, region)
+			if err != nil {
+				err = fmt.Errorf("failed to discover %ss in %s: %w", j.Type, region, err)
+				errs = append(errs, err)
+				var ok bool
+				if t, ok = d.last[lastKey]; !ok {
+					level.Error(d.logger).Log("msg", "Skipping discovery job.", "job", i, "region", region, "error", err)
+					continue
+				}
+				level.Error(d.logger).Log("msg", "Using previous discovery job results.", "job", i, "region", region, "error", err)
+			} else {
+				d.last[lastKey] = t
+			}
+
+			// first matching job wins
+			for _, instance := range t.Instances {
+				key := instance.Region + "/" + instance.Instance
+				if _, ok := seenInstances[key]; ok {
+					continue
+				}
+				seenInstances[key] = struct{}{}
+				res.Instances = append(res.Instances, instance)
+			}
+			for _, cluster := range t.Clusters {
+				key := cluster.Region + "/" + cluster.Cluster
+				if _, ok := seenClusters[key]; ok {
+					continue
+				}
+				seenClusters[key] = struct{}{}
+				res.Clusters = append(res.Clusters, cluster)
+			}
+		}
+	}
+
+	if attempts > 0 && len(errs) == attempts {
+		return Targets{}, errors.Join(errs...)
+	}
+
+	sort.Slice(res.Instances, func(i, j int) bool {
+		if res.Instances[i].Region != res.Instances[j].Region {
+			return res.Instances[i].Region < res.Instances[j].Region
+		}
+		return res.Instances[i].Instance < res.Instances[j].Instance
+	})
+	sort.Slice(res.Clusters, func(i, j int) bool {
+		if res.Clusters[i].Region != res.Clusters[j].Region {
+			return res.Clusters[i].Region < res.Clusters[j].Region
+		}
+		return res.Clusters[i].Cluster < res.Clusters[j].Cluster
+	})
+	return res, nil
+}
+
+// newRDS returns RDS client for a single job in a single region.
+func (d *Discoverer) newRDS(j *job, region string) (*rds.Client, error) {
+	cfg, err := sessions.LoadConfig(j.Instance(region, ""), d.client, d.trace, d.logger)
+	if err != nil {
+		return nil, err
+	}
+	return rds.NewFromConfig(cfg), nil
+}
+
+// engineFilters returns RDS API filters for job's engines.
+func (j *job) engineFilters() []types.Filter {
+	if len(j.Engines) == 0 {
+		return nil
+	}
+	return []types.Filter{{
+		Name:   aws.String("engine"),
+		Values: j.Engines,
+	}}
+}
+
+// discoverInstances returns instances matching a single job in a single region.
+func (d *Discoverer) discoverInstances(ctx context.Context, j *job, region string) ([]config.Instance, error) {
+	svc, err := d.newRDS(j, region)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []config.Instance
+	paginator := rds.NewDescribeDBInstancesPaginator(svc, &rds.DescribeDBInstancesInput{
+		Filters: j.engineFilters(),
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dbInstance := range output.DBInstances {
+			name := aws.ToString(dbInstance.DBInstanceIdentifier)
+			if !j.match(name, dbInstance.TagList) {
+				continue
+			}
+
+			instance := j.Instance(region, name)
+			j.labels(instance.Labels, dbInstance.TagList)
+			res = append(res, instance)
+		}
+	}
+
+	return res, nil
+}
+
+// discoverClusters returns clusters matching a single job in a single region.
+func (d *Discoverer) discoverClusters(ctx context.Context, j *job, region string) ([]config.Cluster, error) {
+	svc, err := d.newRDS(j, region)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []config.Cluster
+	paginator := rds.NewDescribeDBClustersPaginator(svc, &rds.DescribeDBClustersInput{
+		Filters: j.engineFilters(),
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dbCluster := range output.DBClusters {
+			name := aws.ToString(dbCluster.DBClusterIdentifier)
+			if !j.match(name, dbCluster.TagList) {
+				continue
+			}
+
+			cluster := j.Cluster(region, name)
+			j.labels(cluster.Labels, dbCluster.TagList)
+			res = append(res, cluster)
+		}
+	}
+
+	return res, nil
+}
+
+// Start discovers instances and clusters in loop until context is canceled.
+// Targets are sent to the channel only when they are changed.
+func (d *Discoverer) Start(ctx context.Context, interval time.Duration, last Targets, ch chan<- Targets) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// nothing
+		case <-ctx.Done():
+			return
+		}
+
+		discoverCtx, cancel := context.WithTimeout(ctx, interval)
+		targets, err := d.Discover(discoverCtx)
+		cancel()
+		if err != nil {
+			level.Error(d.logger).Log("msg", "Failed to discover instances.", "error", err)
+			continue
+		}
+
+		if reflect.DeepEqual(last, targets) {
+			level.Debug(d.logger).Log("msg", "Discovered targets are not changed.")
+			continue
+		}
+
+		level.Info(d.logger).Log("msg", fmt.Sprintf("Discovered %d instances and %d clusters.", len(targets.Instances), len(targets.Clusters)))
+		last = targets
+		select {
+		case ch <- targets:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Merge returns static instances and clusters followed by discovered ones not present in static configuration.
+func Merge(static *config.Config, discovered Targets) Targets {
+	res := Targets{
+		Instances: make([]config.Instance, 0, len(static.Instances)+len(discovered.Instances)),
+		Clusters:  make([]config.Cluster, 0, len(static.Clusters)+len(discovered.Clusters)),
+	}
+	res.Instances = append(res.Instances, static.Instances...)
+	res.Clusters = append(res.Clusters, static.Clusters...)
+
+	seen := make(map[string]struct{}, len(static.Instances))
+	for _, instance := range static.Instances {
+		seen[instance.Region+"/"+instance.Instance] = struct{}{}
+	}
+	for _, instance := range discovered.Instances {
+		if _, ok := seen[instance.Region+"/"+instance.Instance]; ok {
+			continue
+		}
+		res.Instances = append(res.Instances, instance)
+	}
+
+	seen = make(map[string]struct{}, len(static.Clusters))
+	for _, cluster := range static.Clusters {
+		seen[cluster.Region+"/"+cluster.Cluster] = struct{}{}
+	}
+	for _, cluster := range discovered.Clusters {
+		if _, ok := seen[cluster.Region+"/"+cluster.Cluster]; ok {
+			continue
+		}
+		res.Clusters = append(res.Clusters, cluster)
+	}
+
+	return res
+}
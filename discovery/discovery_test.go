@@ -0,0 +1,279 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/common/promlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/rds_exporter/config"
+)
+
+type fakeDBInstance struct {
+	name   string
+	engine string
+	tags   map[string]string
+}
+
+// fakeRDS implements a subset of RDS DescribeDBInstances and DescribeDBClusters APIs (query protocol)
+// with engine filter and pagination. The same instances are returned as clusters.
+type fakeRDS struct {
+	pageSize  int
+	instances []fakeDBInstance
+}
+
+func (f *fakeRDS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	action := r.PostForm.Get("Action")
+	var kind string
+	switch action {
+	case "DescribeDBInstances":
+		kind = "DBInstance"
+	case "DescribeDBClusters":
+		kind = "DBCluster"
+	default:
+		http.Error(w, "unexpected action "+action, http.StatusBadRequest)
+		return
+	}
+
+	engines := make(map[string]bool)
+	if r.PostForm.Get("Filters.Filter.1.Name") == "engine" {
+		for i := 1; r.PostForm.Get(fmt.Sprintf("Filters.Filter.1.Values.Value.%d", i)) != ""; i++ {
+			engines[r.PostForm.Get(fmt.Sprintf("Filters.Filter.1.Values.Value.%d", i))] = true
+		}
+	}
+	var instances []fakeDBInstance
+	for _, instance := range f.instances {
+		if len(engines) == 0 || engines[instance.engine] {
+			instances = append(instances, instance)
+		}
+	}
+
+	start, _ := strconv.Atoi(r.PostForm.Get("Marker"))
+	end := start + f.pageSize
+	var marker string
+	if end < len(instances) {
+		marker = fmt.Sprintf("<Marker>%d</Marker>", end)
+	} else {
+		end = len(instances)
+	}
+
+	var res strings.Builder
+	for _, instance := range instances[start:end] {
+		fmt.Fprintf(&res, "<%[1]s><%[1]sIdentifier>%[2]s</%[1]sIdentifier><Engine>%[3]s</Engine><TagList>", kind, instance.name, instance.engine)
+		for k, v := range instance.tags {
+			fmt.Fprintf(&res, "<Tag><Key>%s</Key><Value>%s</Value></Tag>", k, v)
+		}
+		fmt.Fprintf(&res, "</TagList></%s>", kind)
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprintf(w, `<%[1]sResponse xmlns="http://rds.amazonaws.com/doc/2014-10-31/">`+
+		`<%[1]sResult><%[2]ss>%[3]s</%[2]ss>%[4]s</%[1]sResult>`+
+		`<ResponseMetadata><RequestId>00000000-0000-0000-0000-000000000000</RequestId></ResponseMetadata>`+
+		`</%[1]sResponse>`, action, kind, res.String(), marker)
+}
+
+// setupFakeEndpoint makes AWS SDK use given endpoint and fake credentials.
+func setupFakeEndpoint(t *testing.T, url string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("AWS_ENDPOINT_URL", url)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+	t.Setenv("AWS_CA_BUNDLE", "")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+}
+
+func newFakeRDS() *fakeRDS {
+	return &fakeRDS{
+		pageSize: 2,
+		instances: []fakeDBInstance{
+			{name: "prod-mysql", engine: "mysql", tags: map[string]string{"env": "prod", "team": "db"}},
+			{name: "prod-aurora", engine: "aurora-mysql", tags: map[string]string{"env": "prod"}},
+			{name: "dev-mysql", engine: "mysql", tags: map[string]string{"env": "dev", "team": "db"}},
+			{name: "prod-postgres", engine: "postgres", tags: map[string]string{"env": "production", "team": "web"}},
+			{name: "prod-untagged", engine: "postgres"},
+		},
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	srv := httptest.NewServer(newFakeRDS())
+	defer srv.Close()
+	setupFakeEndpoint(t, srv.URL)
+
+	logger := promlog.New(&promlog.Config{})
+	d, err := New(config.Discovery{
+		Jobs: []config.DiscoveryJob{{
+			Regions:   []string{"us-east-1"},
+			Engines:   []string{"mysql", "postgres"},
+			Names:     []string{"^prod-"},
+			Tags:      map[string]string{"env": "^prod"},
+			TagLabels: map[string]string{"team": "team"},
+			Labels:    map[string]string{"foo": "bar"},
+		}},
+	}, http.DefaultClient, logger, false)
+	require.NoError(t, err)
+
+	targets, err := d.Discover(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, targets.Clusters)
+	expected := []config.Instance{{
+		Region:   "us-east-1",
+		Instance: "prod-mysql",
+		Labels:   map[string]string{"foo": "bar", "team": "db"},
+	}, {
+		Region:   "us-east-1",
+		Instance: "prod-postgres",
+		Labels:   map[string]string{"foo": "bar", "team": "web"},
+	}}
+	assert.Equal(t, expected, targets.Instances)
+}
+
+func TestDiscoverPartialFailure(t *testing.T) {
+	fake := newFakeRDS()
+	var fail atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// region is a part of the signature credential scope
+		if fail.Load() && strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/") {
+			http.Error(w, "AccessDenied", http.StatusForbidden)
+			return
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	setupFakeEndpoint(t, srv.URL)
+
+	logger := promlog.New(&promlog.Config{})
+	d, err := New(config.Discovery{
+		Jobs: []config.DiscoveryJob{{
+			Regions: []string{"eu-west-1", "us-east-1"},
+			Names:   []string{"^prod-mysql$"},
+		}},
+	}, http.DefaultClient, logger, false)
+	require.NoError(t, err)
+
+	expected := []config.Instance{
+		{Region: "eu-west-1", Instance: "prod-mysql", Labels: map[string]string{}},
+		{Region: "us-east-1", Instance: "prod-mysql", Labels: map[string]string{}},
+	}
+	targets, err := d.Discover(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, expected, targets.Instances)
+
+	// previous results are kept for failed region
+	fail.Store(true)
+	targets, err = d.Discover(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, expected, targets.Instances)
+
+	// failed region without previous results is skipped
+	d, err = New(config.Discovery{
+		Jobs: []config.DiscoveryJob{{
+			Regions: []string{"eu-west-1", "us-east-1"},
+			Names:   []string{"^prod-mysql$"},
+		}},
+	}, http.DefaultClient, logger, false)
+	require.NoError(t, err)
+	targets, err = d.Discover(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, expected[1:], targets.Instances)
+
+	d, err = New(config.Discovery{
+		Jobs: []config.DiscoveryJob{{Regions: []string{"eu-west-1"}}},
+	}, http.DefaultClient, logger, false)
+	require.NoError(t, err)
+
+	_, err = d.Discover(context.Background())
+	assert.Error(t, err)
+}
+
+func TestDiscoverClusters(t *testing.T) {
+	srv := httptest.NewServer(newFakeRDS())
+	defer srv.Close()
+	setupFakeEndpoint(t, srv.URL)
+
+	logger := promlog.New(&promlog.Config{})
+	d, err := New(config.Discovery{
+		Jobs: []config.DiscoveryJob{{
+			Type:      config.DiscoveryTypeCluster,
+			Regions:   []string{"us-east-1"},
+			Engines:   []string{"aurora-mysql"},
+			TagLabels: map[string]string{"env": "env"},
+		}},
+	}, http.DefaultClient, logger, false)
+	require.NoError(t, err)
+
+	targets, err := d.Discover(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, targets.Instances)
+	expected := []config.Cluster{{
+		Region:  "us-east-1",
+		Cluster: "prod-aurora",
+		Labels:  map[string]string{"env": "prod"},
+	}}
+	assert.Equal(t, expected, targets.Clusters)
+}
+
+func TestNewInvalid(t *testing.T) {
+	logger := promlog.New(&promlog.Config{})
+	for name, job := range map[string]config.DiscoveryJob{
+		"no regions": {},
+		"type":       {Type: "proxy", Regions: []string{"us-east-1"}},
+		"name":       {Regions: []string{"us-east-1"}, Names: []string{"("}},
+		"tag":        {Regions: []string{"us-east-1"}, Tags: map[string]string{"env": "("}},
+		"label":      {Regions: []string{"us-east-1"}, TagLabels: map[string]string{"team": "team-name"}},
+	} {
+		_, err := New(config.Discovery{Jobs: []config.DiscoveryJob{job}}, http.DefaultClient, logger, false)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	static := &config.Config{
+		Instances: []config.Instance{
+			{Region: "us-east-1", Instance: "a", Labels: map[string]string{"static": "1"}},
+		},
+		Clusters: []config.Cluster{
+			{Region: "us-east-1", Cluster: "c", Labels: map[string]string{"static": "1"}},
+		},
+	}
+	discovered := Targets{
+		Instances: []config.Instance{
+			{Region: "us-east-1", Instance: "a"},
+			{Region: "us-east-1", Instance: "b"},
+			{Region: "us-west-2", Instance: "a"},
+		},
+		Clusters: []config.Cluster{
+			{Region: "us-east-1", Cluster: "c"},
+			{Region: "us-east-1", Cluster: "d"},
+		},
+	}
+	expected := Targets{
+		Instances: []config.Instance{
+			{Region: "us-east-1", Instance: "a", Labels: map[string]string{"static": "1"}},
+			{Region: "us-east-1", Instance: "b"},
+			{Region: "us-west-2", Instance: "a"},
+		},
+		Clusters: []config.Cluster{
+			{Region: "us-east-1", Cluster: "c", Labels: map[string]string{"static": "1"}},
+			{Region: "us-east-1", Cluster: "d"},
+		},
+	}
+	assert.Equal(t, expected, Merge(static, discovered))
+}
@@ -0,0 +1,113 @@
+package enhanced
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// withTimestamp returns metrics with given explicit timestamp.
+func withTimestamp(metrics []prometheus.Metric, timestamp time.Time) []prometheus.Metric {
+	res := make([]prometheus.Metric, len(metrics))
+	for i, m := range metrics {
+		res[i] = prometheus.NewMetricWithTimestamp(timestamp, m)
+	}
+	return res
+}
+
+// bufferedEvents returns a copy of all buffered events.
+// Buffer is not cleared; old events are removed only when buffer_size is exceeded.
+func (c *Collector) bufferedEvents() map[string][]event {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+
+	res := make(map[string][]event, len(c.buffer))
+	for id, events := range c.buffer {
+		res[id] = append([]event(nil), events...)
+	}
+	return res
+}
+
+// gatherBuffer returns metric families of all buffered events.
+// Samples of the same series are sorted by timestamp.
+func (c *Collector) gatherBuffer() ([]*dto.MetricFamily, error) {
+	families := make(map[string]*dto.MetricFamily)
+	for _, events := range c.bufferedEvents() {
+		for _, e := range events {
+			// every event contains each series once, so it can be gathered by a registry
+			registry := prometheus.NewRegistry()
+			if err := registry.Register(metricsCollector(e.metrics)); err != nil {
+				return nil, err
+			}
+			mfs, err := registry.Gather()
+			if err != nil {
+				return nil, err
+			}
+
+			for _, mf := range mfs {
+				if f := families[mf.GetName()]; f != nil {
+					f.Metric = append(f.Metric, mf.Metric...)
+					continue
+				}
+				families[mf.GetName()] = mf
+			}
+		}
+	}
+
+	res := make([]*dto.MetricFamily, 0, len(families))
+	for _, mf := range families {
+		sort.SliceStable(mf.Metric, func(i, j int) bool { return mf.Metric[i].GetTimestampMs() < mf.Metric[j].GetTimestampMs() })
+		res = append(res, mf)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].GetName() < res[j].GetName() })
+	return res, nil
+}
+
+// BufferHandler returns HTTP handler that exposes metrics of all buffered events with their event timestamps.
+// Events are buffered only in the buffer timestamps mode.
+func (c *Collector) BufferHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		mfs, err := c.gatherBuffer()
+		if err != nil {
+			level.Error(c.logger).Log("msg", "Failed to gather buffered metrics.", "error", err)
+			http.Error(rw, fmt.Sprintf("Failed to gather buffered metrics: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		format := expfmt.Negotiate(req.Header)
+		rw.Header().Set("Content-Type", string(format))
+		enc := expfmt.NewEncoder(rw, format)
+		for _, mf := range mfs {
+			if err := enc.Encode(mf); err != nil {
+				level.Error(c.logger).Log("msg", "Failed to encode buffered metrics.", "error", err)
+				return
+			}
+		}
+	})
+}
+
+// metricsCollector is an unchecked collector for already collected metrics.
+type metricsCollector []prometheus.Metric
+
+// Describe implements prometheus.Collector.
+func (mc metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	// unchecked collector
+}
+
+// Collect implements prometheus.Collector.
+func (mc metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range mc {
+		ch <- m
+	}
+}
+
+// check interfaces
+var (
+	_ prometheus.Collector = metricsCollector(nil)
+)
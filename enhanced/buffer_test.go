@@ -0,0 +1,75 @@
+package enhanced
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/health"
+)
+
+func TestBuffer(t *testing.T) {
+	m, err := parseEvent(readTestDataJSON(t, "mysql-57"), true)
+	require.NoError(t, err)
+	metrics := m.makePrometheusMetrics("us-west-2", nil, config.Enhanced{})
+
+	const id = "db-test"
+	t1 := time.Now().Add(-time.Minute).Truncate(time.Second).UTC() // not stale
+	t2 := t1.Add(time.Second)
+	t3 := t2.Add(time.Second)
+	result := func(timestamps ...time.Time) scrapeResult {
+		res := scrapeResult{
+			metrics:    map[string][]prometheus.Metric{id: metrics},
+			timestamps: map[string]time.Time{id: timestamps[len(timestamps)-1]},
+			events:     make(map[string][]event),
+		}
+		for _, ts := range timestamps {
+			res.events[id] = append(res.events[id], event{timestamp: ts, metrics: metrics})
+		}
+		return res
+	}
+
+	c := &Collector{
+		logger:     log.NewNopLogger(),
+		metrics:    make(map[string][]prometheus.Metric),
+		timestamps: make(map[string]time.Time),
+		buffer:     make(map[string][]event),
+		messages:   make(map[string]string),
+		config:     config.Enhanced{Timestamps: config.TimestampsBuffer, BufferSize: 2},
+		status:     health.NewTracker("enhanced"),
+	}
+	c.setMetrics(result(t1))
+	c.setMetrics(result(t1, t2, t3)) // t1 is scraped again, and dropped as buffer is full
+	require.Len(t, c.buffer[id], 2)
+	assert.Equal(t, []time.Time{t2, t3}, []time.Time{c.buffer[id][0].timestamp, c.buffer[id][1].timestamp})
+
+	// the latest metrics have event timestamp
+	ch := make(chan prometheus.Metric, len(metrics)+10)
+	c.Collect(ch)
+	close(ch)
+	var pb dto.Metric
+	require.NoError(t, (<-ch).Write(&pb))
+	assert.Equal(t, t3.UnixMilli(), pb.GetTimestampMs())
+
+	rec := httptest.NewRecorder()
+	c.BufferHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/enhanced/buffer", nil))
+	body := rec.Body.String()
+	assert.Equal(t, 1, strings.Count(body, "# TYPE rdsosmetrics_General_numVCPUs gauge"))
+	assert.Contains(t, body, fmt.Sprintf(`rdsosmetrics_General_numVCPUs{instance="autotest-mysql-57",region="us-west-2"} 2 %d`+"\n"+
+		`rdsosmetrics_General_numVCPUs{instance="autotest-mysql-57",region="us-west-2"} 2 %d`, t2.UnixMilli(), t3.UnixMilli()))
+
+	// buffer is not cleared by requests
+	rec = httptest.NewRecorder()
+	c.BufferHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/enhanced/buffer", nil))
+	assert.Equal(t, body, rec.Body.String())
+	assert.Len(t, c.buffer[id], 2)
+}
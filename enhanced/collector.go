@@ -3,25 +3,104 @@ package enhanced
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/health"
 	"github.com/percona/rds_exporter/sessions"
 )
 
 // Collector collects enhanced RDS metrics by utilizing several scrapers.
 type Collector struct {
-	sessions *sessions.Sessions
-	logger   log.Logger
+	logger log.Logger
 
-	rw      sync.RWMutex
-	metrics map[string][]prometheus.Metric
+	rw         sync.RWMutex
+	metrics    map[string][]prometheus.Metric
+	timestamps map[string]time.Time         // ResourceID -> latest event timestamp
+	instances  map[string]sessions.Instance // ResourceID -> instance
+	buffer     map[string][]event           // ResourceID -> buffered events with timestamped metrics, oldest first
+	messages   map[string]string            // ResourceID -> the latest raw message
+	config     config.Enhanced
+
+	status *health.Tracker
+
+	m      sync.Mutex
+	groups map[string]*scraperGroup // session and polling interval -> running scrapers
+	closed bool
+
+	pending atomic.Int64 // scrapers started before readiness that haven't finished their first scrapes
+	ready   atomic.Bool  // the first scrapes after startup are finished, and collector is not closed
+}
+
+// scraperGroup contains scrapers of instances sharing AWS config and polling interval.
+type scraperGroup struct {
+	awsCfg    aws.Config
+	interval  time.Duration
+	instances []sessions.Instance
+	config    config.Enhanced
+
+	scrapers []*scraper
+	cancel   context.CancelFunc // stops scrapers
+	wg       sync.WaitGroup     // waits for scrapers
+}
+
+// stop cancels group scrapers, including their in-flight requests, and waits for them.
+func (g *scraperGroup) stop() {
+	g.cancel()
+	g.wg.Wait()
+}
+
+// nextStartTime returns the earliest next start time of stopped group scrapers.
+func (g *scraperGroup) nextStartTime() time.Time {
+	var res time.Time
+	for _, s := range g.scrapers {
+		if res.IsZero() || s.nextStartTime.Before(res) {
+			res = s.nextStartTime
+		}
+	}
+	return res
+}
+
+// sameScraperInstances returns true if both lists contain the same instances with the same labels in the same order.
+// Other instance fields don't affect enhanced metrics.
+func sameScraperInstances(a, b []sessions.Instance) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Region != b[i].Region || a[i].Instance != b[i].Instance || a[i].ResourceID != b[i].ResourceID {
+			return false
+		}
+		if !reflect.DeepEqual(a[i].Labels, b[i].Labels) {
+			return false
+		}
+	}
+	return true
 }
 
+var latestEventAgeDesc = prometheus.NewDesc(
+	"rds_exporter_enhanced_latest_event_age_seconds",
+	"Time since the latest enhanced monitoring event of the instance, in seconds.",
+	[]string{"region", "instance", "resource_id"},
+	nil,
+)
+
+var staleInstancesDesc = prometheus.NewDesc(
+	"rds_exporter_enhanced_stale_instances",
+	"Number of instances with the latest enhanced monitoring event older than staleness TTL; their metrics are not exported.",
+	nil,
+	nil,
+)
+
 // Maximal and minimal metrics update interval.
 const (
 	maxInterval = 60 * time.Second
@@ -29,43 +108,245 @@ const (
 )
 
 // NewCollector creates new collector and starts scrapers.
-func NewCollector(sessions *sessions.Sessions, logger log.Logger) *Collector {
+func NewCollector(cfg *config.Config, sessions *sessions.Sessions, logger log.Logger) *Collector {
 	c := &Collector{
-		sessions: sessions,
-		logger:   log.With(logger, "component", "enhanced"),
-		metrics:  make(map[string][]prometheus.Metric),
+		logger:     log.With(logger, "component", "enhanced"),
+		metrics:    make(map[string][]prometheus.Metric),
+		timestamps: make(map[string]time.Time),
+		buffer:     make(map[string][]event),
+		messages:   make(map[string]string),
+		status:     health.NewTracker("enhanced"),
+	}
+	c.Update(cfg, sessions)
+	return c
+}
+
+// Update updates scrapers for given configuration and sessions, one group per session and polling interval.
+// Only groups with changed instances or settings are restarted; others keep running.
+// Metrics of instances that are not present in new sessions are removed.
+// It does nothing after Close.
+func (c *Collector) Update(cfg *config.Config, sess *sessions.Sessions) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.closed {
+		return
 	}
 
-	for session, instances := range sessions.AllSessions() {
-		enabledInstances := getEnabledInstances(instances)
-		cfg := sessions.Configs[session]
-		s := newScraper(cfg, enabledInstances, logger)
+	instances := make(map[string]sessions.Instance)
+	enabled := make(map[string]bool)
+	for _, sessionInstances := range sess.AllSessions() {
+		for _, instance := range getEnabledInstances(sessionInstances) {
+			instances[instance.ResourceID] = instance
+			enabled[instance.Region+"/"+instance.Instance] = true
+		}
+	}
+	c.rw.Lock()
+	for id := range c.metrics {
+		if _, ok := instances[id]; !ok {
+			delete(c.metrics, id)
+		}
+	}
+	for id := range c.timestamps {
+		if _, ok := instances[id]; !ok {
+			delete(c.timestamps, id)
+		}
+	}
+	for id := range c.messages {
+		if _, ok := instances[id]; !ok {
+			delete(c.messages, id)
+		}
+	}
+	for id := range c.buffer {
+		if _, ok := instances[id]; !ok || cfg.Enhanced.GetTimestamps() != config.TimestampsBuffer {
+			delete(c.buffer, id)
+		}
+	}
+	c.instances = instances
+	c.config = cfg.Enhanced
+	c.rw.Unlock()
+	c.status.Retain(func(region, instance string) bool {
+		return enabled[region+"/"+instance]
+	})
 
-		interval := maxInterval
-		for _, instance := range enabledInstances {
-			if instance.EnhancedMonitoringInterval > 0 && instance.EnhancedMonitoringInterval < interval {
-				interval = instance.EnhancedMonitoringInterval
+	groups := make(map[string]*scraperGroup)
+	if cfg.Enhanced.GetIngestion() == config.IngestionFirehose {
+		// events are pushed to FirehoseHandler
+		level.Info(c.logger).Log("msg", "Receiving enhanced metrics from Kinesis Data Firehose.")
+	} else {
+		for session, instances := range sess.AllSessions() {
+			for _, group := range groupByInterval(getEnabledInstances(instances)) {
+				groups[session+"/"+group.interval.String()] = &scraperGroup{
+					awsCfg:    sess.Configs[session],
+					interval:  group.interval,
+					instances: group.instances,
+					config:    cfg.Enhanced,
+				}
 			}
 		}
-		if interval < minInterval {
-			interval = minInterval
+	}
+	c.updateGroups(groups)
+
+	// nothing to wait for
+	if c.pending.Load() == 0 {
+		c.ready.Store(true)
+	}
+}
+
+// updateGroups stops running scraper groups that are not present in given groups or changed,
+// and starts new groups. Restarted groups continue from the next start time of stopped ones.
+// It should be called with locked mutex.
+func (c *Collector) updateGroups(groups map[string]*scraperGroup) {
+	nextStartTimes := make(map[string]time.Time)
+	for key, running := range c.groups {
+		if g := groups[key]; g != nil && g.config == running.config && sameScraperInstances(g.instances, running.instances) {
+			groups[key] = running
+			continue
+		}
+
+		running.stop()
+		nextStartTimes[key] = running.nextStartTime()
+		delete(c.groups, key)
+	}
+
+	if c.groups == nil {
+		c.groups = make(map[string]*scraperGroup)
+	}
+	for key, g := range groups {
+		if c.groups[key] == g {
+			continue
+		}
+		c.startScrapers(g, nextStartTimes[key])
+		c.groups[key] = g
+	}
+}
+
+// Ready returns true if the first scrapes of all scrapers started after startup are finished
+// (successfully or not), and collector is not closed. Scrapers restarted later don't affect readiness.
+func (c *Collector) Ready() bool {
+	return c.ready.Load()
+}
+
+// startScrapers starts scrapers of given group. Non-zero nextStartTime is used for their first scrapes.
+func (c *Collector) startScrapers(g *scraperGroup, nextStartTime time.Time) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g.cancel = cancel
+
+	// live tail session supports a limited number of log streams
+	liveTail := g.config.GetIngestion() == config.IngestionLiveTail
+	chunks := [][]sessions.Instance{g.instances}
+	if liveTail {
+		chunks = splitInstances(g.instances, maxLiveTailStreams)
+	}
+
+	for _, chunk := range chunks {
+		s := newScraper(g.awsCfg, chunk, c.logger)
+		s.status = c.status
+		s.config = g.config
+		if !nextStartTime.IsZero() {
+			s.nextStartTime = nextStartTime
+		}
+		g.scrapers = append(g.scrapers, s)
+		waitFirst := !c.ready.Load()
+		if waitFirst {
+			c.pending.Add(1)
 		}
-		level.Info(s.logger).Log("msg", fmt.Sprintf("Updating enhanced metrics every %s.", interval))
 
-		// perform first scrapes synchronously so returned collector has all metric descriptions
-		m, _ := s.scrape(context.TODO())
-		c.setMetrics(m)
+		if liveTail {
+			level.Info(s.logger).Log("msg", "Receiving enhanced metrics with live tail.")
+		} else {
+			level.Info(s.logger).Log("msg", fmt.Sprintf("Updating enhanced metrics every %s.", g.interval), "instances", len(chunk))
+		}
 
-		ch := make(chan map[string][]prometheus.Metric)
+		ch := make(chan scrapeResult)
+		g.wg.Add(2)
+		go func() {
+			defer g.wg.Done()
+			for res := range ch {
+				c.setMetrics(res)
+				if waitFirst {
+					waitFirst = false
+					if c.pending.Add(-1) == 0 {
+						c.ready.Store(true)
+					}
+				}
+			}
+		}()
 		go func() {
-			for m := range ch {
-				c.setMetrics(m)
+			defer g.wg.Done()
+			defer close(ch)
+
+			// the first scrape is performed immediately
+			ch <- s.scrape(ctx)
+			if liveTail {
+				s.startLiveTail(ctx, g.interval, ch)
+			} else {
+				s.start(ctx, g.interval, ch)
 			}
 		}()
-		go s.start(context.TODO(), interval, ch)
 	}
+}
 
-	return c
+// pollInterval returns polling interval for given instance: configured override,
+// or enhanced monitoring interval limited by maxInterval, but not less than minInterval.
+func pollInterval(instance sessions.Instance) time.Duration {
+	interval := instance.EnhancedInterval
+	if interval == 0 {
+		interval = instance.EnhancedMonitoringInterval
+		if interval == 0 || interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+	if interval < minInterval {
+		interval = minInterval
+	}
+	return interval
+}
+
+// intervalGroup contains instances polled with the same interval.
+type intervalGroup struct {
+	interval  time.Duration
+	instances []sessions.Instance
+}
+
+// groupByInterval groups instances by polling interval, shortest first.
+func groupByInterval(instances []sessions.Instance) []intervalGroup {
+	groups := make(map[time.Duration][]sessions.Instance)
+	for _, instance := range instances {
+		interval := pollInterval(instance)
+		groups[interval] = append(groups[interval], instance)
+	}
+
+	res := make([]intervalGroup, 0, len(groups))
+	for interval, instances := range groups {
+		res = append(res, intervalGroup{interval: interval, instances: instances})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].interval < res[j].interval })
+	return res
+}
+
+// stop cancels all running scrapers, including their in-flight requests, and waits for them.
+// It should be called with locked mutex.
+func (c *Collector) stop() {
+	for _, g := range c.groups {
+		g.cancel()
+	}
+	for _, g := range c.groups {
+		g.wg.Wait()
+	}
+	c.groups = nil
+}
+
+// Close stops running scrapers and waits for them, and makes collector not ready.
+// The latest metrics are still available.
+func (c *Collector) Close() {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.ready.Store(false)
+	c.closed = true
+	c.stop()
+	c.ready.Store(false) // scrapers could set it while stopping
 }
 
 func getEnabledInstances(instances []sessions.Instance) []sessions.Instance {
@@ -80,15 +361,71 @@ func getEnabledInstances(instances []sessions.Instance) []sessions.Instance {
 	return enabledInstances
 }
 
-// setMetrics saves latest scraped metrics.
-func (c *Collector) setMetrics(m map[string][]prometheus.Metric) {
+// setMetrics saves latest scraped metrics and their timestamps, buffers new events if enabled,
+// and drops metrics of stale instances. Events older than already saved ones (for example, from retried
+// or reordered Firehose batches) are ignored.
+func (c *Collector) setMetrics(res scrapeResult) {
 	c.rw.Lock()
-	for id, metrics := range m {
-		c.metrics[id] = metrics
+	if c.config.GetTimestamps() == config.TimestampsBuffer {
+		size := c.config.GetBufferSize()
+		for id, events := range res.events {
+			// the latest event may be scraped again
+			latest := c.timestamps[id]
+			buffer := c.buffer[id]
+			for _, e := range events {
+				if !e.timestamp.After(latest) {
+					continue
+				}
+				buffer = append(buffer, event{timestamp: e.timestamp, metrics: withTimestamp(e.metrics, e.timestamp)})
+			}
+			if len(buffer) > size {
+				buffer = buffer[len(buffer)-size:]
+			}
+			c.buffer[id] = buffer
+		}
+	}
+	for id, timestamp := range res.timestamps {
+		if timestamp.Before(c.timestamps[id]) {
+			continue
+		}
+		c.timestamps[id] = timestamp
+		c.metrics[id] = res.metrics[id]
+		c.messages[id] = res.messages[id]
+	}
+
+	// timestamps are kept for the latest event age
+	now := time.Now()
+	for id := range c.metrics {
+		if c.isStale(id, now) {
+			level.Debug(c.logger).Log("msg", "Dropping stale metrics.", "resource_id", id, "timestamp", c.timestamps[id].UTC())
+			delete(c.metrics, id)
+		}
 	}
 	c.rw.Unlock()
 }
 
+// isStale returns true if the latest event of given instance is older than staleness TTL.
+// It should be called with read or write lock held.
+func (c *Collector) isStale(id string, now time.Time) bool {
+	timestamp, ok := c.timestamps[id]
+	return ok && now.Sub(timestamp) > c.config.GetStalenessTTL()
+}
+
+// Status returns the latest scrape status of given instance, or false if it is unknown.
+func (c *Collector) Status(region, instance string) (health.Status, bool) {
+	return c.status.Status(region, instance)
+}
+
+// LastEvent returns timestamp and raw message of the latest event of instance with given ResourceID,
+// or false if there are no events.
+func (c *Collector) LastEvent(resourceID string) (time.Time, string, bool) {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+
+	timestamp, ok := c.timestamps[resourceID]
+	return timestamp, c.messages[resourceID], ok
+}
+
 // Describe implements prometheus.Collector.
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
 	// unchecked collector
@@ -99,11 +436,60 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 	c.rw.RLock()
 	defer c.rw.RUnlock()
 
-	for _, metrics := range c.metrics {
+	// metrics may become stale between updates, for example when Firehose stops delivering events
+	now := time.Now()
+	withTimestamps := c.config.GetTimestamps() != config.TimestampsScrape
+	for id, metrics := range c.metrics {
+		if c.isStale(id, now) {
+			continue
+		}
+		timestamp, ok := c.timestamps[id]
 		for _, m := range metrics {
+			if withTimestamps && ok {
+				m = prometheus.NewMetricWithTimestamp(timestamp, m)
+			}
 			ch <- m
 		}
 	}
+
+	var stale int
+	for id, timestamp := range c.timestamps {
+		instance := c.instances[id]
+		ch <- prometheus.MustNewConstMetric(latestEventAgeDesc, prometheus.GaugeValue, now.Sub(timestamp).Seconds(),
+			instance.Region, instance.Instance, id)
+		if c.isStale(id, now) {
+			stale++
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(staleInstancesDesc, prometheus.GaugeValue, float64(stale))
+
+	c.status.Collect(ch)
+}
+
+// Probe returns enhanced metrics of a single instance: the latest scraped ones if that instance is monitored
+// by this collector, or metrics scraped once using given AWS config otherwise.
+func (c *Collector) Probe(ctx context.Context, cfg aws.Config, instance sessions.Instance) ([]prometheus.Metric, error) {
+	c.rw.RLock()
+	metrics, ok := c.metrics[instance.ResourceID]
+	stale := c.isStale(instance.ResourceID, time.Now())
+	c.rw.RUnlock()
+	if ok && !stale {
+		return metrics, nil
+	}
+
+	if instance.EnhancedMonitoringInterval == 0 {
+		return nil, fmt.Errorf("enhanced monitoring is disabled for %s", instance)
+	}
+
+	s := newScraper(cfg, []sessions.Instance{instance}, c.logger)
+	c.rw.RLock()
+	s.config = c.config
+	c.rw.RUnlock()
+	res := s.scrape(ctx)
+	if len(res.metrics[instance.ResourceID]) == 0 {
+		return nil, fmt.Errorf("no enhanced metrics for %s", instance)
+	}
+	return res.metrics[instance.ResourceID], nil
 }
 
 // check interfaces
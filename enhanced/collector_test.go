@@ -0,0 +1,254 @@
+package enhanced
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/health"
+	"github.com/percona/rds_exporter/sessions"
+)
+
+func TestGroupByInterval(t *testing.T) {
+	instances := []sessions.Instance{
+		{Instance: "fast", EnhancedMonitoringInterval: time.Second},
+		{Instance: "slow1", EnhancedMonitoringInterval: time.Minute},
+		{Instance: "slow2", EnhancedMonitoringInterval: time.Minute},
+		{Instance: "disabled"},
+		{Instance: "override", EnhancedMonitoringInterval: time.Second, EnhancedInterval: 5 * time.Minute},
+	}
+	groups := groupByInterval(instances)
+	require.Len(t, groups, 3)
+
+	names := func(g intervalGroup) []string {
+		res := make([]string, 0, len(g.instances))
+		for _, i := range g.instances {
+			res = append(res, i.Instance)
+		}
+		return res
+	}
+	assert.Equal(t, minInterval, groups[0].interval)
+	assert.Equal(t, []string{"fast"}, names(groups[0]))
+	assert.Equal(t, maxInterval, groups[1].interval)
+	assert.Equal(t, []string{"slow1", "slow2", "disabled"}, names(groups[1]))
+	assert.Equal(t, 5*time.Minute, groups[2].interval)
+	assert.Equal(t, []string{"override"}, names(groups[2]))
+}
+
+func TestStaleness(t *testing.T) {
+	m, err := parseEvent(readTestDataJSON(t, "mysql-57"), true)
+	require.NoError(t, err)
+	metrics := m.makePrometheusMetrics("us-west-2", nil, config.Enhanced{})
+
+	c := &Collector{
+		logger:     log.NewNopLogger(),
+		metrics:    make(map[string][]prometheus.Metric),
+		timestamps: make(map[string]time.Time),
+		buffer:     make(map[string][]event),
+		messages:   make(map[string]string),
+		config:     config.Enhanced{StalenessTTL: time.Minute},
+		status:     health.NewTracker("enhanced"),
+	}
+	c.setMetrics(scrapeResult{
+		metrics: map[string][]prometheus.Metric{"db-fresh": metrics, "db-stale": metrics},
+		timestamps: map[string]time.Time{
+			"db-fresh": time.Now().Add(-30 * time.Second),
+			"db-stale": time.Now().Add(-2 * time.Minute),
+		},
+	})
+	assert.Contains(t, c.metrics, "db-fresh")
+	assert.NotContains(t, c.metrics, "db-stale")
+
+	// metrics become stale between updates
+	c.timestamps["db-fresh"] = time.Now().Add(-2 * time.Minute)
+	ch := make(chan prometheus.Metric, len(metrics)+10)
+	c.Collect(ch)
+	close(ch)
+	var stale *dto.Metric
+	for m := range ch {
+		if strings.Contains(m.Desc().String(), "rds_exporter_enhanced_stale_instances") {
+			stale = new(dto.Metric)
+			require.NoError(t, m.Write(stale))
+			continue
+		}
+		assert.Contains(t, m.Desc().String(), "latest_event_age", "stale metric %s", m.Desc())
+	}
+	require.NotNil(t, stale)
+	assert.Equal(t, 2.0, stale.GetGauge().GetValue())
+}
+
+func TestOutOfOrder(t *testing.T) {
+	c := &Collector{
+		logger:     log.NewNopLogger(),
+		metrics:    make(map[string][]prometheus.Metric),
+		timestamps: make(map[string]time.Time),
+		buffer:     make(map[string][]event),
+		messages:   make(map[string]string),
+		status:     health.NewTracker("enhanced"),
+	}
+	newer := time.Now().Add(-time.Second)
+	older := newer.Add(-time.Minute)
+	c.setMetrics(scrapeResult{
+		timestamps: map[string]time.Time{"db-test": newer},
+		messages:   map[string]string{"db-test": "newer"},
+	})
+
+	// retried Firehose batch with an older event
+	c.setMetrics(scrapeResult{
+		timestamps: map[string]time.Time{"db-test": older},
+		messages:   map[string]string{"db-test": "older"},
+	})
+	assert.Equal(t, newer, c.timestamps["db-test"])
+	assert.Equal(t, "newer", c.messages["db-test"])
+}
+
+func TestClose(t *testing.T) {
+	f := &fakeLogs{
+		t:       t,
+		message: string(readTestDataJSON(t, "mysql-57")),
+		targets: make(chan string, 100),
+	}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	cfg := aws.Config{
+		Region:           "us-east-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		BaseEndpoint:     aws.String(srv.URL),
+		HTTPClient:       &http.Client{Timeout: 5 * time.Second},
+		RetryMaxAttempts: 1,
+	}
+	c := &Collector{
+		logger:     log.NewNopLogger(),
+		metrics:    make(map[string][]prometheus.Metric),
+		timestamps: make(map[string]time.Time),
+		buffer:     make(map[string][]event),
+		messages:   make(map[string]string),
+		status:     health.NewTracker("enhanced"),
+	}
+	instances := []sessions.Instance{{Region: "us-east-1", Instance: "test", ResourceID: "db-test"}}
+	c.updateGroups(map[string]*scraperGroup{"test": {awsCfg: cfg, interval: minInterval, instances: instances}})
+	assert.Eventually(t, func() bool {
+		_, _, ok := c.LastEvent("db-test")
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+	g := c.groups["test"]
+
+	done := make(chan struct{})
+	go func() {
+		c.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close didn't stop scrapers")
+	}
+	assert.NotZero(t, g.nextStartTime())
+
+	// no new scrapers after Close
+	c.Update(&config.Config{}, &sessions.Sessions{})
+	assert.Empty(t, c.groups)
+	assert.NotEmpty(t, c.metrics["db-test"])
+}
+
+func TestUpdateGroups(t *testing.T) {
+	f := &fakeLogs{
+		t:       t,
+		message: string(readTestDataJSON(t, "mysql-57")),
+		targets: make(chan string, 100),
+	}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	cfg := aws.Config{
+		Region:           "us-east-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		BaseEndpoint:     aws.String(srv.URL),
+		HTTPClient:       &http.Client{Timeout: 5 * time.Second},
+		RetryMaxAttempts: 1,
+	}
+	c := &Collector{
+		logger:     log.NewNopLogger(),
+		metrics:    make(map[string][]prometheus.Metric),
+		timestamps: make(map[string]time.Time),
+		buffer:     make(map[string][]event),
+		messages:   make(map[string]string),
+		status:     health.NewTracker("enhanced"),
+	}
+	defer c.Close()
+
+	group := func(instances ...string) map[string]*scraperGroup {
+		g := &scraperGroup{awsCfg: cfg, interval: maxInterval}
+		for _, instance := range instances {
+			g.instances = append(g.instances, sessions.Instance{Region: "us-east-1", Instance: instance, ResourceID: "db-" + instance})
+		}
+		return map[string]*scraperGroup{"us-east-1/AKID/1m0s": g}
+	}
+
+	c.updateGroups(group("test1"))
+	running := c.groups["us-east-1/AKID/1m0s"]
+
+	// unchanged group keeps running
+	c.updateGroups(group("test1"))
+	assert.Same(t, running, c.groups["us-east-1/AKID/1m0s"])
+
+	// changed group is restarted
+	c.updateGroups(group("test1", "test2"))
+	assert.NotSame(t, running, c.groups["us-east-1/AKID/1m0s"])
+
+	c.updateGroups(nil)
+	assert.Empty(t, c.groups)
+}
+
+func TestReady(t *testing.T) {
+	f := &fakeLogs{
+		t:       t,
+		message: string(readTestDataJSON(t, "mysql-57")),
+		targets: make(chan string, 100),
+	}
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		<-release
+		f.ServeHTTP(rw, req)
+	}))
+	defer srv.Close()
+
+	cfg := aws.Config{
+		Region:           "us-east-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		BaseEndpoint:     aws.String(srv.URL),
+		HTTPClient:       &http.Client{Timeout: 5 * time.Second},
+		RetryMaxAttempts: 1,
+	}
+	c := &Collector{
+		logger:     log.NewNopLogger(),
+		metrics:    make(map[string][]prometheus.Metric),
+		timestamps: make(map[string]time.Time),
+		buffer:     make(map[string][]event),
+		messages:   make(map[string]string),
+		status:     health.NewTracker("enhanced"),
+	}
+	defer c.Close()
+
+	instances := []sessions.Instance{{Region: "us-east-1", Instance: "test", ResourceID: "db-test"}}
+	c.m.Lock()
+	c.updateGroups(map[string]*scraperGroup{"test": {awsCfg: cfg, interval: maxInterval, instances: instances}})
+	c.m.Unlock()
+	assert.False(t, c.Ready())
+
+	// ready after the first scrape
+	close(release)
+	assert.Eventually(t, c.Ready, 5*time.Second, 10*time.Millisecond)
+}
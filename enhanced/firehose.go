@@ -0,0 +1,196 @@
+package enhanced
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/sessions"
+)
+
+// maxFirehoseRequestSize is the maximal size of Firehose request body.
+// Firehose buffers up to 64 MiB for HTTP endpoints.
+const maxFirehoseRequestSize = 64 << 20
+
+// firehoseRequest is a Kinesis Data Firehose HTTP endpoint delivery request.
+//
+// See https://docs.aws.amazon.com/firehose/latest/dev/httpdeliveryrequestresponse.html
+type firehoseRequest struct {
+	RequestID string `json:"requestId"`
+	Timestamp int64  `json:"timestamp"`
+	Records   []struct {
+		Data []byte `json:"data"` // base64-encoded in JSON
+	} `json:"records"`
+}
+
+// firehoseResponse is a Kinesis Data Firehose HTTP endpoint delivery response.
+type firehoseResponse struct {
+	RequestID    string `json:"requestId"`
+	Timestamp    int64  `json:"timestamp"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+// logsData is a CloudWatch Logs subscription filter message.
+//
+// See https://docs.aws.amazon.com/AmazonCloudWatch/latest/logs/SubscriptionFilters.html#FirehoseExample
+type logsData struct {
+	MessageType string `json:"messageType"` // DATA_MESSAGE or CONTROL_MESSAGE
+	LogGroup    string `json:"logGroup"`
+	LogStream   string `json:"logStream"`
+	LogEvents   []struct {
+		ID        string `json:"id"`
+		Timestamp int64  `json:"timestamp"`
+		Message   string `json:"message"`
+	} `json:"logEvents"`
+}
+
+// decodeLogsData decodes gzip-compressed CloudWatch Logs subscription messages from a single Firehose record.
+// Record may contain several concatenated messages.
+func decodeLogsData(data []byte) ([]logsData, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close() //nolint:errcheck
+
+	var res []logsData
+	d := json.NewDecoder(gr)
+	for {
+		var ld logsData
+		err = d.Decode(&ld)
+		if errors.Is(err, io.EOF) {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, ld)
+	}
+}
+
+// FirehoseHandler returns HTTP handler that implements Kinesis Data Firehose HTTP endpoint delivery protocol
+// for enhanced monitoring log group subscription. It accepts events only in firehose ingestion mode.
+func (c *Collector) FirehoseHandler() http.Handler {
+	l := log.With(c.logger, "component", "firehose")
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requestID := req.Header.Get("X-Amz-Firehose-Request-Id")
+		respond := func(code int, errorMessage string) {
+			if errorMessage != "" {
+				level.Warn(l).Log("msg", "Failed to process request.", "request_id", requestID, "error", errorMessage)
+			}
+			rw.Header().Set("Content-Type", "application/json")
+			rw.WriteHeader(code)
+			json.NewEncoder(rw).Encode(firehoseResponse{ //nolint:errcheck
+				RequestID:    requestID,
+				Timestamp:    time.Now().UnixMilli(),
+				ErrorMessage: errorMessage,
+			})
+		}
+
+		c.rw.RLock()
+		cfg := c.config
+		instances := c.instances
+		c.rw.RUnlock()
+
+		if cfg.GetIngestion() != config.IngestionFirehose {
+			respond(http.StatusNotFound, "Firehose ingestion is disabled.")
+			return
+		}
+		if req.Method != http.MethodPost {
+			rw.Header().Set("Allow", http.MethodPost)
+			respond(http.StatusMethodNotAllowed, "Only POST requests are allowed.")
+			return
+		}
+		key := req.Header.Get("X-Amz-Firehose-Access-Key")
+		if cfg.FirehoseAccessKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(cfg.FirehoseAccessKey)) != 1 {
+			respond(http.StatusUnauthorized, "Invalid access key.")
+			return
+		}
+
+		var body io.Reader = http.MaxBytesReader(rw, req.Body, maxFirehoseRequestSize)
+		if req.Header.Get("Content-Encoding") == "gzip" {
+			gr, err := gzip.NewReader(body)
+			if err != nil {
+				respond(http.StatusBadRequest, fmt.Sprintf("Failed to decompress request: %s.", err))
+				return
+			}
+			defer gr.Close() //nolint:errcheck
+			body = gr
+		}
+
+		var fr firehoseRequest
+		if err := json.NewDecoder(body).Decode(&fr); err != nil {
+			respond(http.StatusBadRequest, fmt.Sprintf("Failed to decode request: %s.", err))
+			return
+		}
+		if requestID == "" {
+			requestID = fr.RequestID
+		}
+
+		res, err := c.receive(fr, instances, cfg, l)
+		if err != nil {
+			respond(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.setMetrics(res)
+		respond(http.StatusOK, "")
+	})
+}
+
+// receive parses enhanced monitoring events from Firehose request records
+// exactly like polled events, for given instances by ResourceID.
+func (c *Collector) receive(fr firehoseRequest, instances map[string]sessions.Instance, cfg config.Enhanced, l log.Logger) (scrapeResult, error) {
+	var messages []logsData
+	for _, r := range fr.Records {
+		m, err := decodeLogsData(r.Data)
+		if err != nil {
+			return scrapeResult{}, fmt.Errorf("failed to decode record: %w", err)
+		}
+		messages = append(messages, m...)
+	}
+
+	// scraper for instances present in the request
+	s := &scraper{
+		logger: l,
+		status: c.status,
+		config: cfg,
+	}
+	seen := make(map[string]bool)
+	acc := newAccumulator()
+	for _, m := range messages {
+		if m.MessageType != "DATA_MESSAGE" || m.LogGroup != cfg.GetLogGroup() {
+			continue
+		}
+		instance, ok := instances[m.LogStream]
+		if !ok {
+			level.Debug(l).Log("msg", "Skipping events of unknown instance.", "LogStreamName", m.LogStream)
+			continue
+		}
+		if !seen[m.LogStream] {
+			seen[m.LogStream] = true
+			s.instances = append(s.instances, instance)
+		}
+
+		for _, e := range m.LogEvents {
+			el := log.With(l,
+				"EventId", e.ID,
+				"LogStreamName", m.LogStream,
+				"Timestamp", time.UnixMilli(e.Timestamp).UTC())
+			s.addEvent(acc, m.LogStream, e.Timestamp, e.Message, el)
+		}
+	}
+
+	s.reportReceived(acc)
+	return s.result(acc), nil
+}
@@ -0,0 +1,122 @@
+package enhanced
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/health"
+	"github.com/percona/rds_exporter/sessions"
+)
+
+func gzipData(t *testing.T, v interface{}) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	require.NoError(t, json.NewEncoder(gw).Encode(v))
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+func TestFirehose(t *testing.T) {
+	const id = "db-QXZYJIL5GR3CBQ4XNCYU2AI5PE"
+	timestamp := time.Now().Truncate(time.Second).UTC() // not stale
+
+	dataMessage := func(logStream, eventID, message string) map[string]interface{} {
+		return map[string]interface{}{
+			"messageType": "DATA_MESSAGE",
+			"logGroup":    "RDSOSMetrics",
+			"logStream":   logStream,
+			"logEvents": []map[string]interface{}{{
+				"id":        eventID,
+				"timestamp": timestamp.UnixMilli(),
+				"message":   message,
+			}},
+		}
+	}
+
+	// CloudWatch Logs may concatenate several gzip-compressed messages in a single record
+	var record []byte
+	for _, m := range []map[string]interface{}{
+		{"messageType": "CONTROL_MESSAGE", "logGroup": "", "logStream": "", "logEvents": []interface{}{}},
+		dataMessage(id, "1", string(readTestDataJSON(t, "mysql-57"))),
+		dataMessage("db-unknown", "2", "{}"),
+	} {
+		record = append(record, gzipData(t, m)...)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"requestId": "request-1",
+		"timestamp": timestamp.UnixMilli(),
+		"records":   []map[string]interface{}{{"data": record}},
+	})
+	require.NoError(t, err)
+
+	c := &Collector{
+		logger:     log.NewNopLogger(),
+		metrics:    make(map[string][]prometheus.Metric),
+		timestamps: make(map[string]time.Time),
+		buffer:     make(map[string][]event),
+		messages:   make(map[string]string),
+		instances: map[string]sessions.Instance{
+			id: {Region: "us-east-1", Instance: "autotest-mysql-57", ResourceID: id},
+		},
+		config: config.Enhanced{Ingestion: config.IngestionFirehose, FirehoseAccessKey: "secret"},
+		status: health.NewTracker("enhanced"),
+	}
+	handler := c.FirehoseHandler()
+
+	post := func(key string) (int, firehoseResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/firehose", bytes.NewReader(body))
+		req.Header.Set("X-Amz-Firehose-Request-Id", "request-1")
+		req.Header.Set("X-Amz-Firehose-Access-Key", key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var resp firehoseResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "request-1", resp.RequestID)
+		assert.NotZero(t, resp.Timestamp)
+		return rec.Code, resp
+	}
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		code, resp := post("wrong")
+		assert.Equal(t, http.StatusUnauthorized, code)
+		assert.NotEmpty(t, resp.ErrorMessage)
+		assert.Empty(t, c.metrics)
+	})
+
+	t.Run("Normal", func(t *testing.T) {
+		code, resp := post("secret")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, resp.ErrorMessage)
+		require.Len(t, c.metrics, 1)
+		assert.NotEmpty(t, c.metrics[id])
+		assert.Equal(t, timestamp, c.timestamps[id])
+
+		latest, message, ok := c.LastEvent(id)
+		assert.True(t, ok)
+		assert.Equal(t, timestamp, latest)
+		assert.JSONEq(t, string(readTestDataJSON(t, "mysql-57")), message)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		c.config.Ingestion = config.IngestionPoll
+		defer func() { c.config.Ingestion = config.IngestionFirehose }()
+
+		code, _ := post("secret")
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+}
@@ -0,0 +1,175 @@
+package enhanced
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/percona/rds_exporter/health"
+	"github.com/percona/rds_exporter/sessions"
+)
+
+// maxLiveTailStreams is the maximal number of log streams in a single StartLiveTail session.
+// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_StartLiveTail.html
+const maxLiveTailStreams = 100
+
+// splitInstances splits instances into chunks of at most given size.
+func splitInstances(instances []sessions.Instance, size int) [][]sessions.Instance {
+	res := make([][]sessions.Instance, 0, len(instances)/size+1)
+	for len(instances) > size {
+		res = append(res, instances[:size])
+		instances = instances[size:]
+	}
+	return append(res, instances)
+}
+
+// startLiveTail receives metrics from StartLiveTail sessions and sends them to the channel until context is canceled.
+// When session ends or fails, events since the last seen timestamp are scraped with FilterLogEvents,
+// and a new session is started after given interval.
+func (s *scraper) startLiveTail(ctx context.Context, interval time.Duration, ch chan<- scrapeResult) {
+	if len(s.logStreamNames) == 0 {
+		// session without stream names would receive events of all instances
+		<-ctx.Done()
+		return
+	}
+
+	for {
+		err := s.liveTail(ctx, ch)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			level.Warn(s.logger).Log("msg", "Live tail session failed, falling back to polling.", "error", err)
+		} else {
+			level.Debug(s.logger).Log("msg", "Live tail session ended.")
+		}
+
+		scrapeCtx, cancel := context.WithTimeout(ctx, interval)
+		res := s.scrape(scrapeCtx)
+		cancel()
+		select {
+		case ch <- res:
+		case <-ctx.Done():
+			return
+		}
+
+		if err != nil {
+			if sleep(ctx, interval) != nil {
+				return
+			}
+		}
+	}
+}
+
+// liveTail runs a single StartLiveTail session, sending received metrics to the channel.
+// It returns nil if session was ended by the server, or error.
+func (s *scraper) liveTail(ctx context.Context, ch chan<- scrapeResult) error {
+	arn, err := s.logGroupARN(ctx)
+	if err != nil {
+		s.fail(err)
+		return err
+	}
+
+	output, err := s.svc.StartLiveTail(ctx, &cloudwatchlogs.StartLiveTailInput{
+		LogGroupIdentifiers: []string{arn},
+		LogStreamNames:      s.logStreamNames,
+	})
+	if err != nil {
+		s.fail(err)
+		return err
+	}
+	stream := output.GetStream()
+	defer stream.Close()
+
+	for e := range stream.Events() {
+		switch e := e.(type) {
+		case *types.StartLiveTailResponseStreamMemberSessionStart:
+			level.Debug(s.logger).Log("msg", "Live tail session started.", "session_id", aws.ToString(e.Value.SessionId))
+
+		case *types.StartLiveTailResponseStreamMemberSessionUpdate:
+			if len(e.Value.SessionResults) == 0 {
+				continue
+			}
+
+			acc := newAccumulator()
+			for _, event := range e.Value.SessionResults {
+				l := log.With(s.logger,
+					"LogStreamName", aws.ToString(event.LogStreamName),
+					"Timestamp", time.UnixMilli(aws.ToInt64(event.Timestamp)).UTC(),
+					"IngestionTime", time.UnixMilli(aws.ToInt64(event.IngestionTime)).UTC())
+				s.addEvent(acc, aws.ToString(event.LogStreamName), aws.ToInt64(event.Timestamp), aws.ToString(event.Message), l)
+			}
+
+			s.reportReceived(acc)
+			select {
+			case ch <- s.result(acc):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	if err = stream.Err(); err != nil {
+		s.fail(err)
+	}
+	return err
+}
+
+// logGroupARN returns cached ARN of enhanced monitoring log group, as required by StartLiveTail.
+func (s *scraper) logGroupARN(ctx context.Context) (string, error) {
+	if s.logGroupArn != "" {
+		return s.logGroupArn, nil
+	}
+
+	logGroup := s.config.GetLogGroup()
+	output, err := s.svc.DescribeLogGroups(ctx, &cloudwatchlogs.DescribeLogGroupsInput{
+		LogGroupNamePrefix: aws.String(logGroup),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe log group: %w", err)
+	}
+	for _, g := range output.LogGroups {
+		if aws.ToString(g.LogGroupName) != logGroup {
+			continue
+		}
+		arn := aws.ToString(g.LogGroupArn)
+		if arn == "" {
+			arn = strings.TrimSuffix(aws.ToString(g.Arn), ":*")
+		}
+		s.logGroupArn = arn
+		return arn, nil
+	}
+	return "", fmt.Errorf("log group %s not found", logGroup)
+}
+
+// fail reports error for all instances.
+func (s *scraper) fail(err error) {
+	if s.status == nil {
+		return
+	}
+	code := health.ErrorCode(err)
+	for _, instance := range s.instances {
+		s.status.Error(instance.Region, instance.Instance, "", code)
+		s.status.Success(instance.Region, instance.Instance, false)
+	}
+}
+
+// sleep waits for given duration or context cancellation.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
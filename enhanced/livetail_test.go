@@ -0,0 +1,137 @@
+package enhanced
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/rds_exporter/health"
+	"github.com/percona/rds_exporter/sessions"
+)
+
+func TestSplitInstances(t *testing.T) {
+	instances := make([]sessions.Instance, 250)
+	chunks := splitInstances(instances, maxLiveTailStreams)
+	require.Len(t, chunks, 3)
+	assert.Len(t, chunks[0], 100)
+	assert.Len(t, chunks[2], 50)
+
+	assert.Len(t, splitInstances(nil, maxLiveTailStreams), 1)
+}
+
+// fakeLogs is a fake CloudWatch Logs endpoint that fails live tail sessions.
+type fakeLogs struct {
+	t       *testing.T
+	message string
+	targets chan string
+}
+
+func (f *fakeLogs) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	target := req.Header.Get("X-Amz-Target")
+	target = target[strings.Index(target, ".")+1:]
+	select {
+	case f.targets <- target:
+	default:
+	}
+
+	rw.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	switch target {
+	case "DescribeLogGroups":
+		fmt.Fprint(rw, `{"logGroups": [{"logGroupName": "RDSOSMetricsOld"}, {"logGroupName": "RDSOSMetrics", `+
+			`"arn": "arn:aws:logs:us-east-1:123456789012:log-group:RDSOSMetrics:*"}]}`)
+
+	case "FilterLogEvents":
+		b, err := json.Marshal(map[string]interface{}{
+			"events": []map[string]interface{}{{
+				"logStreamName": "db-test",
+				"timestamp":     time.Now().UnixMilli(),
+				"message":       f.message,
+			}},
+		})
+		require.NoError(f.t, err)
+		rw.Write(b) //nolint:errcheck
+
+	default:
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(rw, `{"__type": "AccessDeniedException", "message": "denied"}`)
+	}
+}
+
+func TestLiveTailFallback(t *testing.T) {
+	f := &fakeLogs{
+		t:       t,
+		message: string(readTestDataJSON(t, "mysql-57")),
+		targets: make(chan string, 100),
+	}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	cfg := aws.Config{
+		Region:           "us-east-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		BaseEndpoint:     aws.String(srv.URL),
+		HTTPClient:       &http.Client{Timeout: 5 * time.Second},
+		RetryMaxAttempts: 1,
+	}
+	instances := []sessions.Instance{{Region: "us-east-1", Instance: "test", ResourceID: "db-test"}}
+	s := newScraper(cfg, instances, log.NewNopLogger())
+
+	arn, err := s.logGroupARN(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:logs:us-east-1:123456789012:log-group:RDSOSMetrics", arn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan scrapeResult)
+	go s.startLiveTail(ctx, time.Minute, ch)
+
+	// failed session falls back to polling
+	res := <-ch
+	cancel()
+	assert.NotEmpty(t, res.metrics["db-test"])
+	assert.Contains(t, targets(f.targets), "FilterLogEvents")
+}
+
+func TestLiveTailStatus(t *testing.T) {
+	instances := []sessions.Instance{
+		{Region: "us-east-1", Instance: "test-1", ResourceID: "db-test-1"},
+		{Region: "us-east-1", Instance: "test-2", ResourceID: "db-test-2"},
+	}
+	s := newScraper(aws.Config{Region: "us-east-1"}, instances, log.NewNopLogger())
+	s.status = health.NewTracker("enhanced")
+
+	// session update contains events of a single stream
+	acc := newAccumulator()
+	s.addEvent(acc, "db-test-1", time.Now().UnixMilli(), string(readTestDataJSON(t, "mysql-57")), log.NewNopLogger())
+	s.reportReceived(acc)
+
+	st, ok := s.status.Status("us-east-1", "test-1")
+	require.True(t, ok)
+	assert.True(t, st.Success)
+	_, ok = s.status.Status("us-east-1", "test-2")
+	assert.False(t, ok)
+}
+
+// targets returns received requests targets.
+func targets(ch chan string) []string {
+	var res []string
+	for {
+		select {
+		case t := <-ch:
+			res = append(res, t)
+		default:
+			return res
+		}
+	}
+}
@@ -4,11 +4,17 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/percona/rds_exporter/config"
 )
 
 // osMetrics represents available Enhanced Monitoring OS metrics from CloudWatch Logs.
@@ -38,7 +44,7 @@ type osMetrics struct {
 	Swap              swap              `json:"swap"`
 	Tasks             tasks             `json:"tasks"`
 
-	// TODO Handle this: https://jira.percona.com/browse/PMM-3835
+	// physical devices backing logical diskIO devices; same fields as non-Aurora diskIO
 	PhysicalDeviceIO []diskIO `json:"physicalDeviceIO"`
 }
 
@@ -136,9 +142,35 @@ type processList struct {
 	RSS          int     `json:"rss"          help:"The amount of RAM allocated to the process, in kilobytes."`
 	TGID         int     `json:"tgid"         help:"The thread group identifier, which is a number representing the process ID to which a thread belongs. This identifier is used to group threads from the same process."`
 	VSS          int     `json:"vss"          help:"The amount of virtual memory allocated to the process, in kilobytes."`
+	VMLimit      vmLimit `json:"vmlimit"      help:"The virtual memory limit of the process; +Inf if unlimited."`
+}
+
+// vmLimit is a process virtual memory limit: a number, or "unlimited" string.
+type vmLimit float64
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *vmLimit) UnmarshalJSON(b []byte) error {
+	if string(b) == `"unlimited"` {
+		*v = vmLimit(math.Inf(1))
+		return nil
+	}
 
-	// TODO Handle this: https://jira.percona.com/browse/PMM-5150
-	VMLimit interface{} `json:"vmlimit" help:"-"`
+	var f float64
+	if err := json.Unmarshal(b, &f); err != nil {
+		return fmt.Errorf("unexpected vmlimit %s: %w", b, err)
+	}
+	*v = vmLimit(f)
+	return nil
+}
+
+// processGroup represents processes with the same name.
+type processGroup struct {
+	Name         string
+	Count        int
+	CPUUsedPC    float64
+	MemoryUsedPC float64
+	RSS          int
+	VSS          int
 }
 
 //nolint:lll
@@ -161,6 +193,43 @@ type tasks struct {
 	Zombie   int `json:"zombie"   help:"The number of child tasks that are inactive with an active parent task."`
 }
 
+// eventMetrics is a parsed enhanced monitoring event of any supported format.
+type eventMetrics interface {
+	// eventTimestamp returns the time at which the metrics were taken.
+	eventTimestamp() time.Time
+
+	// makePrometheusMetrics returns all Prometheus metrics for the event.
+	makePrometheusMetrics(region string, labels map[string]string, cfg config.Enhanced) []prometheus.Metric
+}
+
+// isWindowsEngine returns true if given engine sends Windows (SQL Server) enhanced monitoring format.
+// Engine may look like "SqlServer", "SQL Server", or "sqlserver-ee".
+func isWindowsEngine(engine string) bool {
+	engine = strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(engine))
+	return strings.HasPrefix(engine, "sqlserver")
+}
+
+// isAuroraEngine returns true if given engine is Aurora MySQL ("Aurora") or Aurora PostgreSQL ("aurora-postgresql").
+func isAuroraEngine(engine string) bool {
+	return strings.HasPrefix(strings.ToLower(engine), "aurora")
+}
+
+// parseEvent parses enhanced monitoring event from given JSON data.
+// Windows format is used for SQL Server, Linux format is used for all other engines (including Oracle).
+func parseEvent(b []byte, disallowUnknownFields bool) (eventMetrics, error) {
+	var header struct {
+		Engine string `json:"engine"`
+	}
+	if err := json.Unmarshal(b, &header); err != nil {
+		return nil, err
+	}
+
+	if isWindowsEngine(header.Engine) {
+		return parseWindowsMetrics(b, disallowUnknownFields)
+	}
+	return parseOSMetrics(b, disallowUnknownFields)
+}
+
 // parseOSMetrics parses OS metrics from given JSON data.
 func parseOSMetrics(b []byte, disallowUnknownFields bool) (*osMetrics, error) {
 	d := json.NewDecoder(bytes.NewReader(b))
@@ -175,6 +244,34 @@ func parseOSMetrics(b []byte, disallowUnknownFields bool) (*osMetrics, error) {
 	return &m, nil
 }
 
+// uptimeRE matches uptime formats: "01:45:58", "1 day, 07:11:58", "732 days, 21:03:05", with optional fractional seconds.
+var uptimeRE = regexp.MustCompile(`^(?:(\d+)\s+days?,?\s*)?(\d+):(\d{1,2}):(\d{1,2}(?:\.\d+)?)$`)
+
+// parseUptime parses uptime string.
+func parseUptime(s string) (time.Duration, error) {
+	m := uptimeRE.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("unexpected uptime format %q", s)
+	}
+
+	var days int64
+	if m[1] != "" {
+		var err error
+		if days, err = strconv.ParseInt(m[1], 10, 64); err != nil {
+			return 0, fmt.Errorf("unexpected uptime format %q: %w", s, err)
+		}
+	}
+	hours, _ := strconv.ParseInt(m[2], 10, 64)
+	minutes, _ := strconv.ParseInt(m[3], 10, 64)
+	seconds, _ := strconv.ParseFloat(m[4], 64)
+	if minutes >= 60 || seconds >= 60 {
+		return 0, fmt.Errorf("unexpected uptime format %q", s)
+	}
+
+	d := time.Duration(days)*24*time.Hour + time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+	return d + time.Duration(seconds*float64(time.Second)), nil
+}
+
 // makeGauge returns Prometheus gauge for given reflect.Value.
 func makeGauge(desc *prometheus.Desc, labelValues []string, value reflect.Value) prometheus.Metric {
 	// skip nil fields
@@ -239,8 +336,9 @@ func makeNodeCPUMetrics(s *cpuUtilization, constLabels prometheus.Labels) []prom
 	return res
 }
 
-// makeRDSDiskIOMetrics returns rdsosmetrics_diskIO_ metrics.
-func makeRDSDiskIOMetrics(s *diskIO, constLabels prometheus.Labels) []prometheus.Metric {
+// makeRDSDiskIOMetrics returns rdsosmetrics_diskIO_ or rdsosmetrics_physicalDeviceIO_ metrics
+// (depending on the section name).
+func makeRDSDiskIOMetrics(s *diskIO, section string, constLabels prometheus.Labels) []prometheus.Metric {
 	// move device name to label
 	labelKeys := []string{"device"}
 	labelValues := []string{s.Device}
@@ -254,7 +352,7 @@ func makeRDSDiskIOMetrics(s *diskIO, constLabels prometheus.Labels) []prometheus
 		if name == "device" {
 			continue
 		}
-		desc := prometheus.NewDesc("rdsosmetrics_diskIO_"+name, help, labelKeys, constLabels)
+		desc := prometheus.NewDesc("rdsosmetrics_"+section+"_"+name, help, labelKeys, constLabels)
 		m := makeGauge(desc, labelValues, v.Field(i))
 		if m != nil {
 			res = append(res, m)
@@ -413,6 +511,68 @@ func makeRDSProcessListMetrics(s *processList, constLabels prometheus.Labels) []
 	return res
 }
 
+// aggregateProcessList returns process list summed by process name.
+func aggregateProcessList(list []processList) []processGroup {
+	res := make([]processGroup, 0, len(list))
+	indexes := make(map[string]int, len(list)) // name -> index in res
+	for _, p := range list {
+		i, ok := indexes[p.Name]
+		if !ok {
+			i = len(res)
+			indexes[p.Name] = i
+			res = append(res, processGroup{Name: p.Name})
+		}
+		g := &res[i]
+		g.Count++
+		g.CPUUsedPC += p.CPUUsedPC
+		g.MemoryUsedPC += p.MemoryUsedPC
+		g.RSS += p.RSS
+		g.VSS += p.VSS
+	}
+	return res
+}
+
+// topProcesses sorts processes (or process groups) by CPU and memory usage in place,
+// and returns the first limit of them (or all if limit is 0).
+func topProcesses[T processList | processGroup | windowsProcess](list []T, limit int, usage func(*T) (cpu, memory float64)) []T {
+	sort.SliceStable(list, func(i, j int) bool {
+		ci, mi := usage(&list[i])
+		cj, mj := usage(&list[j])
+		if ci != cj {
+			return ci > cj
+		}
+		return mi > mj
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
+
+// makeRDSProcessGroupMetrics returns rdsosmetrics_processList_group_ metrics for process group.
+// They have names distinct from per-process metrics, so switching process list mode doesn't change meaning of series.
+func makeRDSProcessGroupMetrics(g *processGroup, constLabels prometheus.Labels) []prometheus.Metric {
+	labelKeys := []string{"name"}
+	labelValues := []string{g.Name}
+
+	res := make([]prometheus.Metric, 0, 5)
+	for _, m := range []struct {
+		name  string
+		help  string
+		value float64
+	}{
+		{"count", "The number of processes and threads with that name.", float64(g.Count)},
+		{"cpuUsedPc", "The percentage of CPU used by processes with that name.", g.CPUUsedPC},
+		{"memoryUsedPc", "The percentage of memory used by processes with that name.", g.MemoryUsedPC},
+		{"rss", "The amount of RAM allocated to processes with that name, in kilobytes.", float64(g.RSS)},
+		{"vss", "The amount of virtual memory allocated to processes with that name, in kilobytes.", float64(g.VSS)},
+	} {
+		desc := prometheus.NewDesc("rdsosmetrics_processList_group_"+m.name, m.help, labelKeys, constLabels)
+		res = append(res, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, m.value, labelValues...))
+	}
+	return res
+}
+
 // makeNodeMemorySwapMetrics returns node_exporter-like node_memory_ metrics for swap.
 func makeNodeMemorySwapMetrics(s *swap, constLabels prometheus.Labels) []prometheus.Metric {
 	t := reflect.TypeOf(*s)
@@ -444,13 +604,12 @@ func makeNodeProcsMetrics(s *tasks, constLabels prometheus.Labels) []prometheus.
 	return res
 }
 
-// makePrometheusMetrics returns all Prometheus metrics for given osMetrics.
-func (m *osMetrics) makePrometheusMetrics(region string, labels map[string]string) []prometheus.Metric {
-	res := make([]prometheus.Metric, 0, 100)
-
+// makeConstLabels returns constant labels for metrics of the instance: region, instance, and given labels
+// (empty values remove labels).
+func makeConstLabels(region, instanceID string, labels map[string]string) prometheus.Labels {
 	constLabels := prometheus.Labels{
 		"region":   region,
-		"instance": m.InstanceID,
+		"instance": instanceID,
 	}
 	for n, v := range labels {
 		if v == "" {
@@ -459,21 +618,54 @@ func (m *osMetrics) makePrometheusMetrics(region string, labels map[string]strin
 			constLabels[n] = v
 		}
 	}
+	return constLabels
+}
+
+// makeGeneralMetrics returns metrics common for all formats: timestamp, uptime, and number of vCPUs.
+// Boot time metric with given name and help is calculated from timestamp and uptime.
+func makeGeneralMetrics(timestamp time.Time, uptime string, numVCPUs int, bootTimeName, bootTimeHelp string, constLabels prometheus.Labels) []prometheus.Metric {
+	res := make([]prometheus.Metric, 0, 4)
 
 	res = append(res, prometheus.MustNewConstMetric(
 		prometheus.NewDesc("rdsosmetrics_timestamp", "Metrics timestamp (UNIX seconds).", nil, constLabels),
 		prometheus.CounterValue,
-		float64(m.Timestamp.Unix()),
+		float64(timestamp.Unix()),
 	))
 
-	// TODO Parse uptime: https://jira.percona.com/browse/PMM-2131
+	if d, err := parseUptime(uptime); err == nil {
+		res = append(res, prometheus.MustNewConstMetric(
+			prometheus.NewDesc("rdsosmetrics_General_uptime_seconds", "The amount of time that the DB instance has been active, in seconds.", nil, constLabels),
+			prometheus.GaugeValue,
+			d.Seconds(),
+		))
+		res = append(res, prometheus.MustNewConstMetric(
+			prometheus.NewDesc(bootTimeName, bootTimeHelp, nil, constLabels),
+			prometheus.GaugeValue,
+			float64(timestamp.Add(-d).Unix()),
+		))
+	}
 
 	res = append(res, prometheus.MustNewConstMetric(
 		prometheus.NewDesc("rdsosmetrics_General_numVCPUs", "The number of virtual CPUs for the DB instance.", nil, constLabels),
 		prometheus.GaugeValue,
-		float64(m.NumVCPUs)),
+		float64(numVCPUs)),
 	)
 
+	return res
+}
+
+// eventTimestamp implements eventMetrics.
+func (m *osMetrics) eventTimestamp() time.Time {
+	return m.Timestamp
+}
+
+// makePrometheusMetrics returns all Prometheus metrics for given osMetrics.
+func (m *osMetrics) makePrometheusMetrics(region string, labels map[string]string, cfg config.Enhanced) []prometheus.Metric {
+	res := make([]prometheus.Metric, 0, 100)
+
+	constLabels := makeConstLabels(region, m.InstanceID, labels)
+	res = append(res, makeGeneralMetrics(m.Timestamp, m.Uptime, m.NumVCPUs, "node_boot_time_seconds", "Node boot time, in unixtime.", constLabels)...)
+
 	// always make both generic and node_exporter-like metrics
 
 	metrics := makeGenericMetrics(m.CPUUtilization, "rdsosmetrics_cpuUtilization_", constLabels)
@@ -482,12 +674,25 @@ func (m *osMetrics) makePrometheusMetrics(region string, labels map[string]strin
 	res = append(res, metrics...)
 
 	for _, disk := range m.DiskIO {
-		metrics = makeRDSDiskIOMetrics(&disk, constLabels)
+		metrics = makeRDSDiskIOMetrics(&disk, "diskIO", constLabels)
 		res = append(res, metrics...)
 		metrics = makeNodeDiskMetrics(&disk, constLabels)
 		res = append(res, metrics...)
 	}
 
+	for _, disk := range m.PhysicalDeviceIO {
+		metrics = makeRDSDiskIOMetrics(&disk, "physicalDeviceIO", constLabels)
+		res = append(res, metrics...)
+
+		// non-Aurora instances report the same disk as logical device with node_disk_ counters;
+		// Aurora logical devices don't have them
+		if !isAuroraEngine(m.Engine) {
+			continue
+		}
+		metrics = makeNodeDiskMetrics(&disk, constLabels)
+		res = append(res, metrics...)
+	}
+
 	for _, fs := range m.FileSys {
 		metrics = makeRDSFileSysMetrics(&fs, constLabels)
 		res = append(res, metrics...)
@@ -511,10 +716,31 @@ func (m *osMetrics) makePrometheusMetrics(region string, labels map[string]strin
 		// we can't make node_exporter-like metrics: AWS gives us rates, node_exporter - total counters
 	}
 
-	for _, p := range m.ProcessList {
-		metrics = makeRDSProcessListMetrics(&p, constLabels)
-		res = append(res, metrics...)
-		// no node_exporter-like metrics
+	// no node_exporter-like metrics for process list
+	switch cfg.ProcessList.GetMode() {
+	case config.ProcessListAggregate:
+		groups := topProcesses(aggregateProcessList(m.ProcessList), cfg.ProcessList.Limit, func(g *processGroup) (float64, float64) {
+			return g.CPUUsedPC, g.MemoryUsedPC
+		})
+		for _, g := range groups {
+			metrics = makeRDSProcessGroupMetrics(&g, constLabels)
+			res = append(res, metrics...)
+		}
+
+	case config.ProcessListDrop:
+		// nothing
+
+	default:
+		list := m.ProcessList
+		if cfg.ProcessList.Limit > 0 {
+			list = topProcesses(append([]processList(nil), list...), cfg.ProcessList.Limit, func(p *processList) (float64, float64) {
+				return p.CPUUsedPC, p.MemoryUsedPC
+			})
+		}
+		for _, p := range list {
+			metrics = makeRDSProcessListMetrics(&p, constLabels)
+			res = append(res, metrics...)
+		}
 	}
 
 	metrics = makeGenericMetrics(m.Swap, "rdsosmetrics_swap_", constLabels)
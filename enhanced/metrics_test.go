@@ -1,12 +1,18 @@
 package enhanced
 
 import (
+	"encoding/json"
+	"math"
 	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/percona/exporter_shared/helpers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/percona/rds_exporter/config"
 )
 
 func TestParse(t *testing.T) {
@@ -15,6 +21,7 @@ func TestParse(t *testing.T) {
 		instance string
 	}{
 		{"us-east-1", "aurora-mysql-56"},
+		{"us-east-1", "aurora-mysql-56-physical"}, // the same event with physicalDeviceIO section in the documented format
 		{"us-west-1", "psql-10"},
 		{"us-west-2", "mysql-57"},
 		{"us-west-2", "aurora-psql-11"},
@@ -23,10 +30,10 @@ func TestParse(t *testing.T) {
 		t.Run(data.instance, func(t *testing.T) {
 			// Test that metrics created from fixed testdata JSON produce expected result.
 
-			m, err := parseOSMetrics(readTestDataJSON(t, data.instance), true)
+			m, err := parseEvent(readTestDataJSON(t, data.instance), true)
 			require.NoError(t, err)
 
-			actualMetrics := helpers.ReadMetrics(m.makePrometheusMetrics(data.region, nil))
+			actualMetrics := helpers.ReadMetrics(m.makePrometheusMetrics(data.region, nil, config.Enhanced{}))
 			sort.Slice(actualMetrics, func(i, j int) bool { return actualMetrics[i].Less(actualMetrics[j]) })
 			actualLines := helpers.Format(helpers.WriteMetrics(actualMetrics))
 
@@ -45,9 +52,112 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestPhysicalDeviceIO(t *testing.T) {
+	nodeDisk := func(engine string) []string {
+		m, err := parseOSMetrics(readTestDataJSON(t, "aurora-mysql-56-physical"), true)
+		require.NoError(t, err)
+		m.Engine = engine
+
+		var res []string
+		for _, metric := range helpers.ReadMetrics(m.makePrometheusMetrics("us-east-1", nil, config.Enhanced{})) {
+			if strings.HasPrefix(metric.Name, "node_disk_") && metric.Labels["device"] == "nvme0n1" {
+				res = append(res, metric.Name)
+			}
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	// the same disk is reported as logical device for non-Aurora instances
+	assert.Equal(t, []string{"node_disk_read_bytes_total", "node_disk_written_bytes_total"}, nodeDisk("Aurora"))
+	assert.Empty(t, nodeDisk("MYSQL"))
+}
+
 func TestParseUptime(t *testing.T) {
-	t.Skip("TODO Parse uptime https://jira.percona.com/browse/PMM-2131")
+	for s, expected := range map[string]time.Duration{
+		"01:45:58":           time.Hour + 45*time.Minute + 58*time.Second,
+		"0:00:05":            5 * time.Second,
+		"1 day, 07:11:58":    31*time.Hour + 11*time.Minute + 58*time.Second,
+		"732 days, 21:03:05": 732*24*time.Hour + 21*time.Hour + 3*time.Minute + 5*time.Second,
+		" 2 days,  00:00:01": 48*time.Hour + time.Second,
+		"00:01:02.50":        time.Minute + 2500*time.Millisecond,
+	} {
+		actual, err := parseUptime(s)
+		assert.NoError(t, err, "%q", s)
+		assert.Equal(t, expected, actual, "%q", s)
+	}
+
+	for _, s := range []string{"", "1 day", "01:61:00", "days, 01:00:00", "-01:00:00"} {
+		_, err := parseUptime(s)
+		assert.Error(t, err, "%q", s)
+	}
+}
+
+func TestProcessList(t *testing.T) {
+	m, err := parseOSMetrics(readTestDataJSON(t, "aurora-mysql-56"), true)
+	require.NoError(t, err)
+
+	// count series and distinct names of processList metrics
+	count := func(cfg config.Enhanced) (series int, names map[string]struct{}) {
+		names = make(map[string]struct{})
+		for _, metric := range helpers.ReadMetrics(m.makePrometheusMetrics("us-east-1", nil, cfg)) {
+			if strings.HasPrefix(metric.Name, "rdsosmetrics_processList_") {
+				series++
+				names[metric.Name] = struct{}{}
+			}
+		}
+		return
+	}
+
+	full, fullNames := count(config.Enhanced{})
+	assert.Equal(t, len(m.ProcessList)*5, full)
+	assert.Contains(t, fullNames, "rdsosmetrics_processList_vmlimit")
+
+	top, _ := count(config.Enhanced{ProcessList: config.ProcessList{Limit: 2}})
+	assert.Equal(t, 2*5, top)
+
+	groups := aggregateProcessList(m.ProcessList)
+	require.Less(t, len(groups), len(m.ProcessList))
+	var processes int
+	for _, g := range groups {
+		processes += g.Count
+	}
+	assert.Equal(t, len(m.ProcessList), processes)
+
+	aggregated, aggregatedNames := count(config.Enhanced{ProcessList: config.ProcessList{Mode: config.ProcessListAggregate}})
+	assert.Equal(t, len(groups)*5, aggregated)
+	assert.Contains(t, aggregatedNames, "rdsosmetrics_processList_group_count")
+	assert.Contains(t, aggregatedNames, "rdsosmetrics_processList_group_cpuUsedPc")
+	for name := range aggregatedNames {
+		assert.NotContains(t, fullNames, name, "aggregated metric with per-process name")
+	}
+
+	aggregated, _ = count(config.Enhanced{ProcessList: config.ProcessList{Mode: config.ProcessListAggregate, Limit: 3}})
+	assert.Equal(t, 3*5, aggregated)
+
+	dropped, _ := count(config.Enhanced{ProcessList: config.ProcessList{Mode: config.ProcessListDrop}})
+	assert.Zero(t, dropped)
+}
+
+func TestVMLimit(t *testing.T) {
+	var p processList
+	require.NoError(t, json.Unmarshal([]byte(`{"vmlimit": "unlimited"}`), &p))
+	assert.True(t, math.IsInf(float64(p.VMLimit), 1))
+	require.NoError(t, json.Unmarshal([]byte(`{"vmlimit": 1024}`), &p))
+	assert.Equal(t, vmLimit(1024), p.VMLimit)
+	assert.Error(t, json.Unmarshal([]byte(`{"vmlimit": "none"}`), &p))
+}
+
+func TestParseEvent(t *testing.T) {
+	for _, engine := range []string{"SQLServer", "SQL Server", "sqlserver-ee"} {
+		assert.True(t, isWindowsEngine(engine), "%s", engine)
+	}
+	for _, engine := range []string{"", "MySQL", "Aurora", "Oracle", "oracle-ee"} {
+		assert.False(t, isWindowsEngine(engine), "%s", engine)
+	}
 
-	_ = "01:45:58"
-	_ = "1 day, 07:11:58"
+	// not a real capture: there is no Oracle golden data yet
+	e, err := parseEvent([]byte(`{"engine": "Oracle", "instanceID": "test"}`), false)
+	require.NoError(t, err)
+	assert.IsType(t, (*osMetrics)(nil), e)
 }
@@ -3,6 +3,7 @@ package enhanced
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -11,6 +12,8 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/health"
 	"github.com/percona/rds_exporter/sessions"
 )
 
@@ -19,8 +22,11 @@ type scraper struct {
 	instances      []sessions.Instance
 	logStreamNames []string
 	svc            *cloudwatchlogs.Client
+	logGroupArn    string // cached for live tail sessions
 	nextStartTime  time.Time
 	logger         log.Logger
+	status         *health.Tracker // may be nil
+	config         config.Enhanced
 
 	testDisallowUnknownFields bool // for tests only
 }
@@ -36,12 +42,27 @@ func newScraper(cfg aws.Config, instances []sessions.Instance, logger log.Logger
 		logStreamNames: logStreamNames,
 		svc:            cloudwatchlogs.NewFromConfig(cfg),
 		nextStartTime:  time.Now().Add(-3 * time.Minute).Round(0), // strip monotonic clock reading
-		logger:         log.With(logger, "component", "enhanced"),
+		logger:         logger,
 	}
 }
 
+// scrapeResult contains the latest metrics, raw messages and their event timestamps by ResourceID,
+// and all scraped events by ResourceID.
+type scrapeResult struct {
+	metrics    map[string][]prometheus.Metric
+	messages   map[string]string
+	timestamps map[string]time.Time
+	events     map[string][]event // oldest first
+}
+
+// event contains metrics of a single enhanced monitoring event.
+type event struct {
+	timestamp time.Time
+	metrics   []prometheus.Metric
+}
+
 // start scrapes metrics in loop and sends them to the channel until context is canceled.
-func (s *scraper) start(ctx context.Context, interval time.Duration, ch chan<- map[string][]prometheus.Metric) {
+func (s *scraper) start(ctx context.Context, interval time.Duration, ch chan<- scrapeResult) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -54,16 +75,16 @@ func (s *scraper) start(ctx context.Context, interval time.Duration, ch chan<- m
 		}
 
 		scrapeCtx, cancel := context.WithTimeout(ctx, interval)
-		m, _ := s.scrape(scrapeCtx)
+		res := s.scrape(scrapeCtx)
 		cancel()
-		ch <- m
+		ch <- res
 	}
 }
 
 // scrape performs a single scrape.
-func (s *scraper) scrape(ctx context.Context) (map[string][]prometheus.Metric, map[string]string) {
-	allMetrics := make(map[string]map[time.Time][]prometheus.Metric) // ResourceID -> event timestamp -> metrics
-	allMessages := make(map[string]map[time.Time]string)             // ResourceID -> event timestamp -> message
+func (s *scraper) scrape(ctx context.Context) scrapeResult {
+	acc := newAccumulator()
+	failed := make(map[string]string) // ResourceID -> error code
 
 	// LogStreamNames parameter supports up to 100 items.
 	// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_FilterLogEvents.html
@@ -76,7 +97,7 @@ func (s *scraper) scrape(ctx context.Context) (map[string][]prometheus.Metric, m
 		}
 
 		input := &cloudwatchlogs.FilterLogEventsInput{
-			LogGroupName:   aws.String("RDSOSMetrics"),
+			LogGroupName:   aws.String(s.config.GetLogGroup()),
 			LogStreamNames: s.logStreamNames[sliceStart:sliceEnd],
 			StartTime:      aws.Int64(s.nextStartTime.UnixMilli()),
 		}
@@ -88,6 +109,9 @@ func (s *scraper) scrape(ctx context.Context) (map[string][]prometheus.Metric, m
 			output, err := paginator.NextPage(ctx)
 			if err != nil {
 				level.Error(s.logger).Log("msg", "Failed to filter log events.", "error", err)
+				for _, id := range s.logStreamNames[sliceStart:sliceEnd] {
+					failed[id] = health.ErrorCode(err)
+				}
 				break
 			}
 			for _, event := range output.Events {
@@ -96,51 +120,105 @@ func (s *scraper) scrape(ctx context.Context) (map[string][]prometheus.Metric, m
 					"LogStreamName", aws.ToString(event.LogStreamName),
 					"Timestamp", time.UnixMilli(aws.ToInt64(event.Timestamp)).UTC(),
 					"IngestionTime", time.UnixMilli(aws.ToInt64(event.IngestionTime)).UTC())
+				s.addEvent(acc, aws.ToString(event.LogStreamName), aws.ToInt64(event.Timestamp), aws.ToString(event.Message), l)
+			}
+		}
+	}
 
-				var instance *sessions.Instance
-				for _, i := range s.instances {
-					if i.ResourceID == aws.ToString(event.LogStreamName) {
-						instance = &i
-						break
-					}
-				}
-				if instance == nil {
-					level.Error(l).Log("msg", "Failed to find instance.")
-					continue
-				}
+	s.reportStatus(failed)
+	return s.result(acc)
+}
 
-				if instance.DisableEnhancedMetrics {
-					level.Debug(l).Log("msg", fmt.Sprintf("Enhanced Metrics are disabled for instance %v.", instance))
-					continue
-				}
-				l = log.With(l, "region", instance.Region, "instance", instance.Instance)
+// accumulator contains parsed metrics and raw messages of log events.
+type accumulator struct {
+	metrics  map[string]map[time.Time][]prometheus.Metric // ResourceID -> event timestamp -> metrics
+	messages map[string]map[time.Time]string              // ResourceID -> event timestamp -> message
+}
 
-				osMetrics, err := parseOSMetrics([]byte(aws.ToString(event.Message)), s.testDisallowUnknownFields)
-				if err != nil {
-					// only for tests
-					if s.testDisallowUnknownFields {
-						panic(fmt.Sprintf("New metrics should be added: %s", err))
-					}
+func newAccumulator() *accumulator {
+	return &accumulator{
+		metrics:  make(map[string]map[time.Time][]prometheus.Metric),
+		messages: make(map[string]map[time.Time]string),
+	}
+}
 
-					level.Error(l).Log("msg", "Failed to parse metrics.", "error", err)
-					continue
-				}
+// addEvent parses log event with given stream name, timestamp (in UNIX milliseconds) and message,
+// and adds it to the accumulator.
+func (s *scraper) addEvent(acc *accumulator, logStreamName string, timestampMs int64, message string, l log.Logger) {
+	var instance *sessions.Instance
+	for _, i := range s.instances {
+		if i.ResourceID == logStreamName {
+			instance = &i
+			break
+		}
+	}
+	if instance == nil {
+		level.Error(l).Log("msg", "Failed to find instance.")
+		return
+	}
 
-				timestamp := time.UnixMilli(aws.ToInt64(event.Timestamp)).UTC()
-				level.Debug(l).Log("msg", fmt.Sprintf("Timestamp from message: %s; from event: %s.", osMetrics.Timestamp.UTC(), timestamp))
+	if instance.DisableEnhancedMetrics {
+		level.Debug(l).Log("msg", fmt.Sprintf("Enhanced Metrics are disabled for instance %v.", instance))
+		return
+	}
+	l = log.With(l, "region", instance.Region, "instance", instance.Instance)
 
-				if allMetrics[instance.ResourceID] == nil {
-					allMetrics[instance.ResourceID] = make(map[time.Time][]prometheus.Metric)
-				}
-				allMetrics[instance.ResourceID][timestamp] = osMetrics.makePrometheusMetrics(instance.Region, instance.Labels)
+	eventMetrics, err := parseEvent([]byte(message), s.testDisallowUnknownFields)
+	if err != nil {
+		// only for tests
+		if s.testDisallowUnknownFields {
+			panic(fmt.Sprintf("New metrics should be added: %s", err))
+		}
 
-				if allMessages[instance.ResourceID] == nil {
-					allMessages[instance.ResourceID] = make(map[time.Time]string)
-				}
-				allMessages[instance.ResourceID][timestamp] = aws.ToString(event.Message)
-			}
+		level.Error(l).Log("msg", "Failed to parse metrics.", "error", err)
+		return
+	}
+
+	timestamp := time.UnixMilli(timestampMs).UTC()
+	level.Debug(l).Log("msg", fmt.Sprintf("Timestamp from message: %s; from event: %s.", eventMetrics.eventTimestamp().UTC(), timestamp))
+
+	if acc.metrics[instance.ResourceID] == nil {
+		acc.metrics[instance.ResourceID] = make(map[time.Time][]prometheus.Metric)
+	}
+	acc.metrics[instance.ResourceID][timestamp] = eventMetrics.makePrometheusMetrics(instance.Region, instance.Labels, s.config)
+
+	if acc.messages[instance.ResourceID] == nil {
+		acc.messages[instance.ResourceID] = make(map[time.Time]string)
+	}
+	acc.messages[instance.ResourceID][timestamp] = message
+}
+
+// reportStatus reports status of all instances: failed ones with given error codes (by ResourceID), others as successful.
+func (s *scraper) reportStatus(failed map[string]string) {
+	if s.status == nil {
+		return
+	}
+	for _, instance := range s.instances {
+		code, ok := failed[instance.ResourceID]
+		if ok {
+			s.status.Error(instance.Region, instance.Instance, "", code)
 		}
+		s.status.Success(instance.Region, instance.Instance, !ok)
 	}
+}
+
+// reportReceived reports success only for instances with accumulated events.
+// It is used for streams where other instances may just have no new events yet.
+func (s *scraper) reportReceived(acc *accumulator) {
+	if s.status == nil {
+		return
+	}
+	for _, instance := range s.instances {
+		if len(acc.metrics[instance.ResourceID]) > 0 {
+			s.status.Success(instance.Region, instance.Instance, true)
+		}
+	}
+}
+
+// result returns scrape result for accumulated events and updates next start time.
+func (s *scraper) result(acc *accumulator) scrapeResult {
+	allMetrics, allMessages := acc.metrics, acc.messages
+
 	// get better times
 	allTimes := make(map[string][]time.Time)
 	for resourceID, events := range allMetrics {
@@ -152,14 +230,26 @@ func (s *scraper) scrape(ctx context.Context) (map[string][]prometheus.Metric, m
 	var times map[string]time.Time
 	times, s.nextStartTime = betterTimes(allTimes)
 
-	// return only latest metrics/messages
-	resMetrics := make(map[string][]prometheus.Metric) // ResourceID -> metrics
-	resMessages := make(map[string]string)             // ResourceID -> message
+	// return only latest metrics/messages, and all events
+	res := scrapeResult{
+		metrics:    make(map[string][]prometheus.Metric),
+		messages:   make(map[string]string),
+		timestamps: times,
+		events:     make(map[string][]event),
+	}
 	for resourceID, timestamp := range times {
-		resMetrics[resourceID] = allMetrics[resourceID][timestamp]
-		resMessages[resourceID] = allMessages[resourceID][timestamp]
+		res.metrics[resourceID] = allMetrics[resourceID][timestamp]
+		res.messages[resourceID] = allMessages[resourceID][timestamp]
+	}
+	for resourceID, timestamps := range allTimes {
+		sort.Slice(timestamps, func(i, j int) bool { return timestamps[i].Before(timestamps[j]) })
+		events := make([]event, len(timestamps))
+		for i, timestamp := range timestamps {
+			events[i] = event{timestamp: timestamp, metrics: allMetrics[resourceID][timestamp]}
+		}
+		res.events[resourceID] = events
 	}
-	return resMetrics, resMessages
+	return res
 }
 
 // betterTimes returns timestamps of the latest metrics, and also StarTime that should be used in the next request
@@ -43,7 +43,7 @@ func TestScraper(t *testing.T) {
 	require.NoError(t, err)
 	logger := promlog.New(&promlog.Config{})
 	client := client.New(logger)
-	sess, err := sessions.New(cfg.Instances, client.HTTP(), logger, false)
+	sess, err := sessions.New(cfg.Instances, cfg.Clusters, client.HTTP(), logger, false)
 	require.NoError(t, err)
 
 	for session, instances := range sess.AllSessions() {
@@ -52,7 +52,8 @@ func TestScraper(t *testing.T) {
 			cfg := sess.Configs[session]
 			s := newScraper(cfg, instances, logger)
 			s.testDisallowUnknownFields = true
-			metrics, messages := s.scrape(context.Background())
+			res := s.scrape(context.Background())
+			metrics, messages := res.metrics, res.messages
 			require.Len(t, metrics, len(instances))
 			require.Len(t, messages, len(instances))
 
@@ -72,9 +73,9 @@ func TestScraper(t *testing.T) {
 					writeTestDataJSON(t, instanceName, []byte(messages[instance.ResourceID]))
 				}
 
-				osMetrics, err := parseOSMetrics(readTestDataJSON(t, instanceName), true)
+				osMetrics, err := parseEvent(readTestDataJSON(t, instanceName), true)
 				require.NoError(t, err)
-				expectedMetrics := helpers.ReadMetrics(osMetrics.makePrometheusMetrics(instance.Region, nil))
+				expectedMetrics := helpers.ReadMetrics(osMetrics.makePrometheusMetrics(instance.Region, nil, config.Enhanced{}))
 				sort.Slice(expectedMetrics, func(i, j int) bool { return expectedMetrics[i].Less(expectedMetrics[j]) })
 				expectedMetrics = filterMetrics(expectedMetrics)
 				expectedLines := helpers.Format(helpers.WriteMetrics(expectedMetrics))
@@ -149,7 +150,7 @@ func TestScraperDisableEnhancedMetrics(t *testing.T) {
 		isDisabled := i%2 == 0
 		cfg.Instances[i].DisableEnhancedMetrics = isDisabled
 	}
-	sess, err := sessions.New(cfg.Instances, client.HTTP(), logger, false)
+	sess, err := sessions.New(cfg.Instances, cfg.Clusters, client.HTTP(), logger, false)
 	require.NoError(t, err)
 
 	// Check if all collected metrics do not contain metrics for instance with disabled metrics.
@@ -167,7 +168,7 @@ func TestScraperDisableEnhancedMetrics(t *testing.T) {
 		t.Run(fmt.Sprint(instances), func(t *testing.T) {
 			s := newScraper(sess.Configs[session], instances, logger)
 			s.testDisallowUnknownFields = true
-			metrics, _ := s.scrape(context.Background())
+			metrics := s.scrape(context.Background()).metrics
 
 			for _, instance := range instances {
 				actualMetrics := helpers.ReadMetrics(metrics[instance.ResourceID])
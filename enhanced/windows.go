@@ -0,0 +1,260 @@
+package enhanced
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/percona/rds_exporter/config"
+)
+
+// windowsMetrics represents available Enhanced Monitoring OS metrics for RDS for SQL Server (Windows).
+//
+// See https://docs.aws.amazon.com/AmazonRDS/latest/UserGuide/USER_Monitoring-Available-OS-Metrics.html#USER_Monitoring-Available-OS-Metrics-RDS.SQLServer
+//
+//nolint:lll
+type windowsMetrics struct {
+	Engine             string    `json:"engine"             help:"The database engine for the DB instance."`
+	InstanceID         string    `json:"instanceID"         help:"The DB instance identifier."`
+	InstanceResourceID string    `json:"instanceResourceID" help:"A region-unique, immutable identifier for the DB instance, also used as the log stream identifier."`
+	NumVCPUs           int       `json:"numVCPUs"           help:"The number of virtual CPUs for the DB instance."`
+	Timestamp          time.Time `json:"timestamp"          help:"The time at which the metrics were taken."`
+	Uptime             string    `json:"uptime"             help:"The amount of time that the DB instance has been active."`
+	Version            float64   `json:"version"            help:"The version of the OS metrics' stream JSON format."`
+
+	CPUUtilization windowsCPUUtilization `json:"cpuUtilization"`
+	Disks          []windowsDisk         `json:"disks"`
+	Memory         windowsMemory         `json:"memory"`
+	Network        []windowsNetwork      `json:"network"`
+	ProcessList    []windowsProcess      `json:"processList"`
+	System         windowsSystem         `json:"system"`
+}
+
+type windowsCPUUtilization struct {
+	Idle float64 `json:"idle" help:"The percentage of CPU that is idle."`
+	Kern float64 `json:"kern" help:"The percentage of CPU in use by the kernel."`
+	User float64 `json:"user" help:"The percentage of CPU in use by user programs."`
+}
+
+//nolint:lll
+type windowsDisk struct {
+	Name      string  `json:"name"      help:"The identifier for the disk."`
+	TotalKb   int64   `json:"totalKb"   help:"The total size of the disk, in kilobytes."`
+	UsedKb    int64   `json:"usedKb"    help:"The amount of space used on the disk, in kilobytes."`
+	UsedPc    float64 `json:"usedPc"    help:"The percentage of space used on the disk."`
+	AvailKb   int64   `json:"availKb"   help:"The space available on the disk, in kilobytes."`
+	AvailPc   float64 `json:"availPc"   help:"The percentage of space available on the disk."`
+	RdCountPS float64 `json:"rdCountPS" help:"The number of read operations per second."`
+	RdBytesPS float64 `json:"rdBytesPS" help:"The number of bytes read per second."`
+	WrCountPS float64 `json:"wrCountPS" help:"The number of write operations per second."`
+	WrBytesPS float64 `json:"wrBytesPS" help:"The number of bytes written per second."`
+}
+
+//nolint:lll
+type windowsMemory struct {
+	CommitTotKb    int64 `json:"commitTotKb"    help:"The amount of pagefile-backed virtual address space in use, that is, the current commit charge, in kilobytes."`
+	CommitLimitKb  int64 `json:"commitLimitKb"  help:"The maximum possible value for the commitTotKb metric, in kilobytes."`
+	CommitPeakKb   int64 `json:"commitPeakKb"   help:"The largest value of the commitTotKb metric since the operating system was last started, in kilobytes."`
+	KernTotKb      int64 `json:"kernTotKb"      help:"The sum of the memory in the paged and non-paged kernel pools, in kilobytes."`
+	KernPagedKb    int64 `json:"kernPagedKb"    help:"The amount of memory in the paged kernel pool, in kilobytes."`
+	KernNonpagedKb int64 `json:"kernNonpagedKb" help:"The amount of memory in the non-paged kernel pool, in kilobytes."`
+	PageSize       int64 `json:"pageSize"       help:"The size of a page, in bytes."`
+	PhysTotKb      int64 `json:"physTotKb"      help:"The amount of physical memory, in kilobytes."`
+	PhysAvailKb    int64 `json:"physAvailKb"    help:"The amount of available physical memory, in kilobytes."`
+	SQLServerTotKb int64 `json:"sqlServerTotKb" help:"The amount of memory committed to SQL Server, in kilobytes."`
+	SysCacheKb     int64 `json:"sysCacheKb"     help:"The amount of system cache memory, in kilobytes."`
+}
+
+type windowsNetwork struct {
+	Interface string  `json:"interface" help:"The identifier for the network interface being used for the DB instance."`
+	RdBytesPS float64 `json:"rdBytesPS" help:"The number of bytes received per second."`
+	WrBytesPS float64 `json:"wrBytesPS" help:"The number of bytes sent per second."`
+}
+
+//nolint:lll
+type windowsProcess struct {
+	Name                  string  `json:"name"                  help:"The name of the process."`
+	PID                   int     `json:"pid"                   help:"The identifier of the process."`
+	PPID                  int     `json:"ppid"                  help:"The process identifier for the parent of this process."`
+	TID                   int     `json:"tid"                   help:"The identifier of the thread."`
+	CPUUsedPc             float64 `json:"cpuUsedPc"             help:"The percentage of CPU used by the process."`
+	MemUsedPc             float64 `json:"memUsedPc"             help:"The percentage of memory used by the process."`
+	WorkingSetKb          int64   `json:"workingSetKb"          help:"The amount of memory in the private working set plus the amount of memory that is in use by the process and can be shared with other processes, in kilobytes."`
+	WorkingSetPrivKb      int64   `json:"workingSetPrivKb"      help:"The amount of memory that is in use by a process, but can't be shared with other processes, in kilobytes."`
+	WorkingSetShareableKb int64   `json:"workingSetShareableKb" help:"The amount of memory that is in use by a process and can be shared with other processes, in kilobytes."`
+	VirtKb                int64   `json:"virtKb"                help:"The amount of virtual address space the process is using, in kilobytes."`
+}
+
+type windowsSystem struct {
+	Handles   int `json:"handles"   help:"The number of handles that the process is using."`
+	Processes int `json:"processes" help:"The number of processes running on the system."`
+	Threads   int `json:"threads"   help:"The number of threads running on the system."`
+}
+
+// parseWindowsMetrics parses Windows OS metrics from given JSON data.
+func parseWindowsMetrics(b []byte, disallowUnknownFields bool) (*windowsMetrics, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	if disallowUnknownFields {
+		d.DisallowUnknownFields()
+	}
+
+	var m windowsMetrics
+	if err := d.Decode(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// makeRDSLabeledMetrics returns rdsosmetrics_ metrics with given name prefix for struct fields,
+// moving fields with given JSON names to labels with given names.
+func makeRDSLabeledMetrics(s interface{}, namePrefix string, fields, labelKeys []string, constLabels prometheus.Labels) []prometheus.Metric {
+	t := reflect.TypeOf(s)
+	v := reflect.ValueOf(s)
+
+	labels := make(map[string]bool, len(fields))
+	labelValues := make([]string, len(fields))
+	for i, field := range fields {
+		labels[field] = true
+		for j := 0; j < t.NumField(); j++ {
+			if t.Field(j).Tag.Get("json") == field {
+				labelValues[i] = toLabelValue(v.Field(j))
+			}
+		}
+	}
+
+	res := make([]prometheus.Metric, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tags := t.Field(i).Tag
+		name, help := tags.Get("json"), tags.Get("help")
+		if labels[name] {
+			continue
+		}
+		desc := prometheus.NewDesc(namePrefix+name, help, labelKeys, constLabels)
+		m := makeGauge(desc, labelValues, v.Field(i))
+		if m != nil {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// toLabelValue returns label value for string or integer reflect.Value.
+func toLabelValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	default:
+		return v.String()
+	}
+}
+
+// makeWindowsMetrics returns windows_exporter-like windows_ metrics.
+func (m *windowsMetrics) makeWindowsMetrics(constLabels prometheus.Labels) []prometheus.Metric {
+	res := make([]prometheus.Metric, 0, 10+2*len(m.Disks))
+	gauge := func(name, help string, value float64, labelKeys []string, labelValues ...string) {
+		desc := prometheus.NewDesc(name, help, labelKeys, constLabels)
+		res = append(res, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labelValues...))
+	}
+
+	gauge("windows_cs_logical_processors", "ComputerSystem.NumberOfLogicalProcessors.", float64(m.NumVCPUs), nil)
+	gauge("windows_cs_physical_memory_bytes", "ComputerSystem.TotalPhysicalMemory.", float64(m.Memory.PhysTotKb*1024), nil)
+	gauge("windows_os_physical_memory_free_bytes", "OperatingSystem.FreePhysicalMemory.", float64(m.Memory.PhysAvailKb*1024), nil)
+	gauge("windows_os_virtual_memory_bytes", "OperatingSystem.TotalVirtualMemorySize.", float64(m.Memory.CommitLimitKb*1024), nil)
+	gauge("windows_os_virtual_memory_free_bytes", "OperatingSystem.FreeVirtualMemory.",
+		float64((m.Memory.CommitLimitKb-m.Memory.CommitTotKb)*1024), nil)
+	gauge("windows_os_processes", "OperatingSystem.NumberOfProcesses.", float64(m.System.Processes), nil)
+	gauge("windows_system_threads", "Current number of threads (WMI source: PerfOS_System.Threads).", float64(m.System.Threads), nil)
+
+	volume := []string{"volume"}
+	for _, d := range m.Disks {
+		gauge("windows_logical_disk_size_bytes", "Total space in bytes (LogicalDisk.PercentFreeSpace_Base).", float64(d.TotalKb*1024), volume, d.Name)
+		gauge("windows_logical_disk_free_bytes", "Free space in bytes (LogicalDisk.PercentFreeSpace).", float64(d.AvailKb*1024), volume, d.Name)
+	}
+
+	return res
+}
+
+// aggregateWindowsProcessList returns Windows process list summed by process name.
+// Working set and virtual memory are reported as RSS and VSS.
+func aggregateWindowsProcessList(list []windowsProcess) []processGroup {
+	converted := make([]processList, len(list))
+	for i, p := range list {
+		converted[i] = processList{
+			Name:         p.Name,
+			CPUUsedPC:    p.CPUUsedPc,
+			MemoryUsedPC: p.MemUsedPc,
+			RSS:          int(p.WorkingSetKb),
+			VSS:          int(p.VirtKb),
+		}
+	}
+	return aggregateProcessList(converted)
+}
+
+// eventTimestamp implements eventMetrics.
+func (m *windowsMetrics) eventTimestamp() time.Time {
+	return m.Timestamp
+}
+
+// makePrometheusMetrics returns all Prometheus metrics for given windowsMetrics.
+func (m *windowsMetrics) makePrometheusMetrics(region string, labels map[string]string, cfg config.Enhanced) []prometheus.Metric {
+	res := make([]prometheus.Metric, 0, 100)
+
+	constLabels := makeConstLabels(region, m.InstanceID, labels)
+	res = append(res, makeGeneralMetrics(m.Timestamp, m.Uptime, m.NumVCPUs, "windows_system_system_up_time", "System boot time (WMI source: PerfOS_System.SystemUpTime).", constLabels)...)
+
+	// always make both generic and windows_exporter-like metrics
+
+	res = append(res, makeGenericMetrics(m.CPUUtilization, "rdsosmetrics_cpuUtilization_", constLabels)...)
+	res = append(res, makeGenericMetrics(m.Memory, "rdsosmetrics_memory_", constLabels)...)
+	res = append(res, makeGenericMetrics(m.System, "rdsosmetrics_system_", constLabels)...)
+
+	for _, d := range m.Disks {
+		res = append(res, makeRDSLabeledMetrics(d, "rdsosmetrics_disks_", []string{"name"}, []string{"name"}, constLabels)...)
+	}
+
+	for _, n := range m.Network {
+		res = append(res, makeRDSLabeledMetrics(n, "rdsosmetrics_network_", []string{"interface"}, []string{"interface"}, constLabels)...)
+	}
+
+	res = append(res, m.makeWindowsMetrics(constLabels)...)
+
+	switch cfg.ProcessList.GetMode() {
+	case config.ProcessListAggregate:
+		groups := topProcesses(aggregateWindowsProcessList(m.ProcessList), cfg.ProcessList.Limit, func(g *processGroup) (float64, float64) {
+			return g.CPUUsedPC, g.MemoryUsedPC
+		})
+		for _, g := range groups {
+			res = append(res, makeRDSProcessGroupMetrics(&g, constLabels)...)
+		}
+
+	case config.ProcessListDrop:
+		// nothing
+
+	default:
+		list := m.ProcessList
+		if cfg.ProcessList.Limit > 0 {
+			list = topProcesses(append([]windowsProcess(nil), list...), cfg.ProcessList.Limit, func(p *windowsProcess) (float64, float64) {
+				return p.CPUUsedPc, p.MemUsedPc
+			})
+		}
+		// use the same label names as Linux process list where meaning is the same;
+		// Windows thread ID is not a thread group ID
+		fields := []string{"name", "pid", "ppid", "tid"}
+		labelKeys := []string{"name", "id", "parentID", "tid"}
+		for _, p := range list {
+			res = append(res, makeRDSLabeledMetrics(p, "rdsosmetrics_processList_", fields, labelKeys, constLabels)...)
+		}
+	}
+
+	return res
+}
+
+// check interfaces
+var (
+	_ eventMetrics = (*osMetrics)(nil)
+	_ eventMetrics = (*windowsMetrics)(nil)
+)
@@ -0,0 +1,61 @@
+package enhanced
+
+import (
+	"testing"
+
+	"github.com/percona/exporter_shared/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/rds_exporter/config"
+)
+
+// windowsEvent is a minimal event in the documented RDS for SQL Server format, not a real capture.
+const windowsEvent = `{
+	"engine": "SQLServer",
+	"instanceID": "test-sqlserver",
+	"instanceResourceID": "db-TEST",
+	"timestamp": "2025-07-02T13:00:06Z",
+	"version": 1.0,
+	"uptime": "3 days, 20:33:49",
+	"numVCPUs": 2,
+	"cpuUtilization": {"idle": 94.87, "kern": 1.37, "user": 3.76},
+	"memory": {"commitTotKb": 3000, "commitLimitKb": 4000, "physTotKb": 4096, "physAvailKb": 1024},
+	"system": {"handles": 24105, "threads": 1165, "processes": 55},
+	"disks": [{"name": "rdsdbdata", "totalKb": 2048, "availKb": 1024}],
+	"network": [{"interface": "Ethernet 3", "rdBytesPS": 1200.46, "wrBytesPS": 3110.62}],
+	"processList": [
+		{"name": "sqlservr.exe", "pid": 2944, "ppid": 624, "tid": 7, "cpuUsedPc": 1.92, "memUsedPc": 52.27},
+		{"name": "OS processes", "pid": 0, "ppid": 0, "tid": 0, "cpuUsedPc": 2.75, "memUsedPc": 9.48},
+		{"name": "OS processes", "pid": 0, "ppid": 0, "tid": 1, "cpuUsedPc": 0.25, "memUsedPc": 0.52}
+	]
+}`
+
+func TestWindowsMetrics(t *testing.T) {
+	e, err := parseEvent([]byte(windowsEvent), true)
+	require.NoError(t, err)
+	require.IsType(t, (*windowsMetrics)(nil), e)
+	assert.Len(t, aggregateWindowsProcessList(e.(*windowsMetrics).ProcessList), 2)
+
+	actual := make(map[string]float64)
+	for _, m := range helpers.ReadMetrics(e.makePrometheusMetrics("us-east-1", nil, config.Enhanced{})) {
+		switch m.Name {
+		case "windows_logical_disk_free_bytes":
+			actual[m.Name+" "+m.Labels["volume"]] = m.Value
+		case "windows_os_virtual_memory_free_bytes", "windows_system_threads":
+			actual[m.Name] = m.Value
+		case "rdsosmetrics_processList_cpuUsedPc":
+			assert.NotContains(t, m.Labels, "tgid")
+			actual[m.Name+" "+m.Labels["name"]+" "+m.Labels["tid"]] = m.Value
+		}
+	}
+	expected := map[string]float64{
+		"windows_logical_disk_free_bytes rdsdbdata":         1024 * 1024,
+		"windows_os_virtual_memory_free_bytes":              1000 * 1024,
+		"windows_system_threads":                            1165,
+		"rdsosmetrics_processList_cpuUsedPc sqlservr.exe 7": 1.92,
+		"rdsosmetrics_processList_cpuUsedPc OS processes 0": 2.75,
+		"rdsosmetrics_processList_cpuUsedPc OS processes 1": 0.25,
+	}
+	assert.Equal(t, expected, actual)
+}
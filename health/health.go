@@ -0,0 +1,163 @@
+// Package health tracks per-instance scrape status of collectors.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Status contains the latest scrape status of a single instance.
+type Status struct {
+	LastScrape    time.Time `json:"last_scrape,omitzero"`     // zero if not scraped yet
+	LastSuccess   time.Time `json:"last_success,omitzero"`    // zero if not scraped successfully yet
+	Success       bool      `json:"success"`                  // the last scrape was successful
+	LastError     string    `json:"last_error,omitempty"`     // AWS error code, prefixed by CloudWatch metric name if applicable
+	LastErrorTime time.Time `json:"last_error_time,omitzero"` // zero if there were no errors
+}
+
+// Tracker keeps per-instance scrape status of a single collector and exposes it as metrics.
+type Tracker struct {
+	m         sync.Mutex
+	instances map[[2]string]*Status // region and instance with status metrics
+
+	mSuccess     *prometheus.GaugeVec
+	mSuccessTime *prometheus.GaugeVec
+	mErrors      *prometheus.CounterVec
+}
+
+// NewTracker creates a new Tracker for collector with given name.
+func NewTracker(collector string) *Tracker {
+	constLabels := prometheus.Labels{"collector": collector}
+	return &Tracker{
+		instances: make(map[[2]string]*Status),
+
+		mSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:        "rds_exporter_scrape_success",
+			Help:        "Whether the last scrape of the instance was successful.",
+			ConstLabels: constLabels,
+		}, []string{"region", "instance"}),
+		mSuccessTime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:        "rds_exporter_last_scrape_success_timestamp_seconds",
+			Help:        "Timestamp of the last successful scrape of the instance.",
+			ConstLabels: constLabels,
+		}, []string{"region", "instance"}),
+		mErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "rds_exporter_scrape_errors_total",
+			Help:        "Total number of scrape errors by CloudWatch metric (if applicable) and AWS error code.",
+			ConstLabels: constLabels,
+		}, []string{"region", "instance", "metric", "code"}),
+	}
+}
+
+// Success records the result of instance's scrape.
+func (t *Tracker) Success(region, instance string, success bool) {
+	now := time.Now()
+	t.update(region, instance, func(s *Status) {
+		s.LastScrape = now
+		s.Success = success
+		if success {
+			s.LastSuccess = now
+		}
+	})
+
+	if !success {
+		t.mSuccess.WithLabelValues(region, instance).Set(0)
+		return
+	}
+	t.mSuccess.WithLabelValues(region, instance).Set(1)
+	t.mSuccessTime.WithLabelValues(region, instance).SetToCurrentTime()
+}
+
+// Error records instance's scrape error for given CloudWatch metric name (may be empty) and error code.
+func (t *Tracker) Error(region, instance, metric, code string) {
+	t.update(region, instance, func(s *Status) {
+		s.LastError = code
+		if metric != "" {
+			s.LastError = metric + ": " + code
+		}
+		s.LastErrorTime = time.Now()
+	})
+
+	t.mErrors.WithLabelValues(region, instance, metric, code).Inc()
+}
+
+// update changes status of given instance with f.
+func (t *Tracker) update(region, instance string, f func(s *Status)) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	key := [2]string{region, instance}
+	s := t.instances[key]
+	if s == nil {
+		s = new(Status)
+		t.instances[key] = s
+	}
+	f(s)
+}
+
+// Status returns the latest scrape status of given instance, or false if it is unknown.
+func (t *Tracker) Status(region, instance string) (Status, bool) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	s := t.instances[[2]string{region, instance}]
+	if s == nil {
+		return Status{}, false
+	}
+	return *s, true
+}
+
+// Retain removes status metrics of instances for which keep returns false.
+func (t *Tracker) Retain(keep func(region, instance string) bool) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	for key := range t.instances {
+		if keep(key[0], key[1]) {
+			continue
+		}
+
+		labels := prometheus.Labels{"region": key[0], "instance": key[1]}
+		t.mSuccess.Delete(labels)
+		t.mSuccessTime.Delete(labels)
+		t.mErrors.DeletePartialMatch(labels)
+		delete(t.instances, key)
+	}
+}
+
+// ErrorCode returns AWS error code for given error, "Timeout" for timeouts, or "Unknown".
+func ErrorCode(err error) string {
+	var apiErr smithy.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.ErrorCode()
+	case errors.Is(err, context.DeadlineExceeded):
+		return "Timeout"
+	default:
+		return "Unknown"
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (t *Tracker) Describe(ch chan<- *prometheus.Desc) {
+	t.mSuccess.Describe(ch)
+	t.mSuccessTime.Describe(ch)
+	t.mErrors.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (t *Tracker) Collect(ch chan<- prometheus.Metric) {
+	t.mSuccess.Collect(ch)
+	t.mSuccessTime.Collect(ch)
+	t.mErrors.Collect(ch)
+}
+
+// check interfaces
+var (
+	_ prometheus.Collector = (*Tracker)(nil)
+)
@@ -0,0 +1,60 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker("basic")
+	tr.Success("us-east-1", "a", true)
+	tr.Error("us-east-1", "b", "CPUUtilization", "Forbidden")
+	tr.Error("us-east-1", "b", "CPUUtilization", "Forbidden")
+	tr.Success("us-east-1", "b", false)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(tr.mSuccess.WithLabelValues("us-east-1", "a")))
+	assert.NotZero(t, testutil.ToFloat64(tr.mSuccessTime.WithLabelValues("us-east-1", "a")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(tr.mSuccess.WithLabelValues("us-east-1", "b")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(tr.mErrors.WithLabelValues("us-east-1", "b", "CPUUtilization", "Forbidden")))
+	assert.Equal(t, 4, testutil.CollectAndCount(tr))
+
+	tr.Retain(func(region, instance string) bool { return instance == "a" })
+	assert.Equal(t, 2, testutil.CollectAndCount(tr))
+}
+
+func TestErrorCode(t *testing.T) {
+	apiErr := &smithy.GenericAPIError{Code: "Throttling", Message: "Rate exceeded"}
+	assert.Equal(t, "Throttling", ErrorCode(fmt.Errorf("operation error: %w", apiErr)))
+	assert.Equal(t, "Timeout", ErrorCode(fmt.Errorf("request: %w", context.DeadlineExceeded)))
+	assert.Equal(t, "Unknown", ErrorCode(errors.New("EOF")))
+}
+
+func TestTrackerStatus(t *testing.T) {
+	tr := NewTracker("enhanced")
+	_, ok := tr.Status("us-east-1", "a")
+	assert.False(t, ok)
+
+	tr.Success("us-east-1", "a", true)
+	tr.Error("us-east-1", "a", "", "ThrottlingException")
+	tr.Success("us-east-1", "a", false)
+	s, ok := tr.Status("us-east-1", "a")
+	assert.True(t, ok)
+	assert.False(t, s.Success)
+	assert.NotZero(t, s.LastSuccess)
+	assert.True(t, s.LastScrape.After(s.LastSuccess) || s.LastScrape.Equal(s.LastSuccess))
+	assert.Equal(t, "ThrottlingException", s.LastError)
+
+	tr.Error("us-east-1", "a", "CPUUtilization", "Forbidden")
+	s, _ = tr.Status("us-east-1", "a")
+	assert.Equal(t, "CPUUtilization: Forbidden", s.LastError)
+
+	tr.Retain(func(region, instance string) bool { return false })
+	_, ok = tr.Status("us-east-1", "a")
+	assert.False(t, ok)
+}
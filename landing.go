@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/common/version"
+)
+
+// endpoint describes a single HTTP endpoint listed on the landing page.
+type endpoint struct {
+	Path        string
+	Description string
+}
+
+var landingTemplate = template.Must(template.New("landing").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>RDS Exporter</title>
+</head>
+<body>
+<h1>RDS Exporter</h1>
+<p>{{ .Version }}</p>
+<h2>Endpoints</h2>
+<ul>
+{{- range .Endpoints }}
+<li><a href="{{ .Path }}">{{ .Path }}</a> - {{ .Description }}</li>
+{{- end }}
+</ul>
+<h2>Instances</h2>
+<pre>{{ .Table }}</pre>
+</body>
+</html>
+`))
+
+// landingHandler returns HTTP handler for the landing page with given endpoints, version and instances table.
+func landingHandler(reloader *reloader, endpoints []endpoint, logger log.Logger) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/" {
+			http.NotFound(rw, req)
+			return
+		}
+
+		var table bytes.Buffer
+		if _, sess := reloader.current(); sess != nil {
+			sess.WriteTable(&table)
+		}
+
+		var buf bytes.Buffer
+		err := landingTemplate.Execute(&buf, map[string]interface{}{
+			"Version":   version.Info(),
+			"Endpoints": endpoints,
+			"Table":     table.String(),
+		})
+		if err != nil {
+			level.Error(logger).Log("msg", "Failed to render landing page.", "error", err)
+			http.Error(rw, "Failed to render landing page.", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = buf.WriteTo(rw)
+	})
+}
+
+// healthyHandler always returns 200 OK while exporter is running.
+func healthyHandler(rw http.ResponseWriter, req *http.Request) {
+	_, _ = rw.Write([]byte("RDS Exporter is Healthy.\n"))
+}
+
+// readyHandler returns 200 OK if sessions are created and the first enhanced metrics scrapes are finished,
+// and 503 Service Unavailable otherwise, including during shutdown.
+func readyHandler(reloader *reloader) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if !reloader.ready() {
+			http.Error(rw, "RDS Exporter is not ready.", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = rw.Write([]byte("RDS Exporter is Ready.\n"))
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/common/promlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/rds_exporter/client"
+)
+
+func TestLanding(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(filename, []byte("---\ninstances: []\n"), 0666))
+
+	logger := promlog.New(&promlog.Config{})
+	r := newReloader(filename, client.New(logger), logger, false)
+
+	get := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	ready := readyHandler(r)
+	assert.Equal(t, http.StatusServiceUnavailable, get(ready, "/-/ready").Code)
+	assert.Equal(t, http.StatusOK, get(http.HandlerFunc(healthyHandler), "/-/healthy").Code)
+
+	require.NoError(t, r.init())
+	assert.Equal(t, http.StatusOK, get(ready, "/-/ready").Code)
+
+	landing := landingHandler(r, []endpoint{{"/basic", "basic <metrics>"}}, logger)
+	rec := get(landing, "/")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `<a href="/basic">/basic</a> - basic &lt;metrics&gt;`)
+	assert.Contains(t, rec.Body.String(), "Region  Instance  Resource ID  Interval")
+	assert.Equal(t, http.StatusNotFound, get(landing, "/foo").Code)
+
+	r.close()
+	assert.Equal(t, http.StatusServiceUnavailable, get(ready, "/-/ready").Code)
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -12,21 +16,31 @@ import (
 	"github.com/prometheus/common/promlog"
 	"github.com/prometheus/common/promlog/flag"
 	"github.com/prometheus/common/version"
+	"github.com/prometheus/exporter-toolkit/web"
 	"gopkg.in/alecthomas/kingpin.v2"
 
-	"github.com/percona/rds_exporter/basic"
 	"github.com/percona/rds_exporter/client"
-	"github.com/percona/rds_exporter/config"
-	"github.com/percona/rds_exporter/enhanced"
-	"github.com/percona/rds_exporter/sessions"
 )
 
 //nolint:lll
 var (
 	listenAddressF       = kingpin.Flag("web.listen-address", "Address on which to expose metrics and web interface.").Default(":9042").String()
+	webConfigFileF       = kingpin.Flag("web.config.file", "Path to configuration file that can enable TLS or authentication.").Default("").String()
+	bearerTokenFileF     = kingpin.Flag("web.bearer-token-file", "Path to file with bearer token required for all endpoints except Firehose, health and readiness ones.").Default("").String()
 	basicMetricsPathF    = kingpin.Flag("web.basic-telemetry-path", "Path under which to expose exporter's basic metrics.").Default("/basic").String()
 	enhancedMetricsPathF = kingpin.Flag("web.enhanced-telemetry-path", "Path under which to expose exporter's enhanced metrics.").Default("/enhanced").String()
+	enhancedBufferPathF  = kingpin.Flag("web.enhanced-buffer-path", "Path under which to expose buffered enhanced metrics events with their timestamps.").Default("/enhanced/buffer").String()
+	firehosePathF        = kingpin.Flag("web.firehose-path", "Path under which to receive enhanced metrics from Kinesis Data Firehose.").Default("/firehose").String()
+	statusPathF          = kingpin.Flag("web.status-path", "Path under which to expose sessions, instances and their latest scrape results.").Default("/status").String()
+	probePathF           = kingpin.Flag("web.probe-path", "Path under which to expose single instance metrics.").Default("/probe").String()
+	shutdownTimeoutF     = kingpin.Flag("web.shutdown-timeout", "Maximal time to wait for active requests on shutdown.").Default("30s").Duration()
 	configFileF          = kingpin.Flag("config.file", "Path to configuration file.").Default("config.yml").String()
+	retryMaxAttemptsF    = kingpin.Flag("aws.retry-max-attempts", "Maximal number of attempts for a single AWS API request, 1 disables retries.").Default("3").Int()
+	attemptTimeoutF      = kingpin.Flag("aws.attempt-timeout", "Timeout of a single attempt of AWS API request until response headers are received, 0 disables it.").Default("15s").Duration()
+	retryMinBackoffF     = kingpin.Flag("aws.retry-min-backoff", "Delay before the first retry of AWS API request, doubled for every next one.").Default("500ms").Duration()
+	retryMaxBackoffF     = kingpin.Flag("aws.retry-max-backoff", "Maximal delay between retries of AWS API request.").Default("10s").Duration()
+	rateLimitF           = kingpin.Flag("aws.rate-limit", "Maximal AWS API requests per second for each AWS account (or access key if account is unknown), 0 disables the limit.").Default("20").Float64()
+	rateBurstF           = kingpin.Flag("aws.rate-burst", "Maximal AWS API requests burst for each AWS account (or access key if account is unknown).").Default("40").Int()
 	logTraceF            = kingpin.Flag("log.trace", "Enable verbose tracing of AWS requests (will log credentials).").Default("false").Bool()
 	logger               = log.NewNopLogger()
 )
@@ -41,23 +55,62 @@ func main() {
 	level.Info(logger).Log("msg", fmt.Sprintf("Starting RDS exporter %s", version.Info()))
 	level.Info(logger).Log("msg", fmt.Sprintf("Build context %s", version.BuildContext()))
 
-	cfg, err := config.Load(*configFileF)
-	if err != nil {
-		level.Error(logger).Log("msg", "Can't read configuration file", "error", err)
-		os.Exit(1)
+	if *webConfigFileF != "" {
+		if err := web.Validate(*webConfigFileF); err != nil {
+			level.Error(logger).Log("msg", "Can't load web configuration", "error", err)
+			os.Exit(1)
+		}
+	}
+	if *bearerTokenFileF != "" {
+		if _, err := readBearerToken(*bearerTokenFileF); err != nil {
+			level.Error(logger).Log("msg", "Can't load bearer token", "error", err)
+			os.Exit(1)
+		}
 	}
 
-	client := client.New(logger)
-	sess, err := sessions.New(cfg.Instances, client.HTTP(), logger, *logTraceF)
-	if err != nil {
-		level.Error(logger).Log("msg", "Can't create sessions", "error", err)
+	client := client.NewWithConfig(client.Config{
+		MaxAttempts:    *retryMaxAttemptsF,
+		AttemptTimeout: *attemptTimeoutF,
+		MinBackoff:     *retryMinBackoffF,
+		MaxBackoff:     *retryMaxBackoffF,
+		RateLimit:      *rateLimitF,
+		RateBurst:      *rateBurstF,
+	}, logger)
+	reloader := newReloader(*configFileF, client, logger, *logTraceF)
+	if err := reloader.init(); err != nil {
+		level.Error(logger).Log("msg", "Can't load configuration", "error", err)
 		os.Exit(1)
 	}
 
+	// reload configuration on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := reloader.reload(); err != nil {
+				level.Error(logger).Log("msg", "Can't reload configuration", "error", err)
+			}
+		}
+	}()
+
+	http.HandleFunc("/-/reload", func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost && req.Method != http.MethodPut {
+			rw.Header().Set("Allow", "POST, PUT")
+			http.Error(rw, "Only POST or PUT requests allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := reloader.reload(); err != nil {
+			level.Error(logger).Log("msg", "Can't reload configuration", "error", err)
+			http.Error(rw, fmt.Sprintf("Failed to reload configuration: %s", err), http.StatusInternalServerError)
+			return
+		}
+	})
+
 	// basic metrics + client metrics + exporter own metrics (ProcessCollector and GoCollector)
 	{
-		prometheus.MustRegister(basic.New(cfg, sess, logger))
+		prometheus.MustRegister(reloader.basic)
 		prometheus.MustRegister(client)
+		prometheus.MustRegister(reloader)
 		http.Handle(*basicMetricsPathF, promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
 			//ErrorLog:      log.NewErrorLogger(), TODO TS
 			ErrorHandling: promhttp.ContinueOnError,
@@ -67,15 +120,79 @@ func main() {
 	// enhanced metrics
 	{
 		registry := prometheus.NewRegistry()
-		registry.MustRegister(enhanced.NewCollector(sess, logger))
+		registry.MustRegister(reloader.enhanced)
 		http.Handle(*enhancedMetricsPathF, promhttp.HandlerFor(registry, promhttp.HandlerOpts{
 			//ErrorLog:      log.NewErrorLogger(), TODO TS
 			ErrorHandling: promhttp.ContinueOnError,
 		}))
+		http.Handle(*enhancedBufferPathF, reloader.enhanced.BufferHandler())
+		http.Handle(*firehosePathF, reloader.enhanced.FirehoseHandler())
 	}
 
+	// single instance metrics
+	http.Handle(*probePathF, newProber(reloader, client, logger, *logTraceF))
+
+	// landing page, health and readiness
+	http.Handle("/", landingHandler(reloader, []endpoint{
+		{*basicMetricsPathF, "basic metrics and exporter's own metrics"},
+		{*enhancedMetricsPathF, "enhanced metrics"},
+		{*probePathF, "single instance metrics"},
+		{*statusPathF, "sessions, instances and their latest scrape results"},
+		{"/-/healthy", "health check"},
+		{"/-/ready", "readiness check"},
+	}, logger))
+	http.Handle(*statusPathF, statusHandler(reloader, logger))
+	http.HandleFunc("/-/healthy", healthyHandler)
+	http.Handle("/-/ready", readyHandler(reloader))
+
 	level.Info(logger).Log("msg", fmt.Sprintf("Basic metrics   : http://%s%s", *listenAddressF, *basicMetricsPathF))
 	level.Info(logger).Log("msg", fmt.Sprintf("Enhanced metrics: http://%s%s", *listenAddressF, *enhancedMetricsPathF))
+	level.Info(logger).Log("msg", fmt.Sprintf("Enhanced buffer : http://%s%s", *listenAddressF, *enhancedBufferPathF))
+	level.Info(logger).Log("msg", fmt.Sprintf("Firehose        : http://%s%s", *listenAddressF, *firehosePathF))
+	level.Info(logger).Log("msg", fmt.Sprintf("Probe           : http://%s%s", *listenAddressF, *probePathF))
+	level.Info(logger).Log("msg", fmt.Sprintf("Status          : http://%s%s", *listenAddressF, *statusPathF))
+
+	// bearer token is not required for Firehose endpoint (it has own access key), health and readiness checks
+	var handler http.Handler = http.DefaultServeMux
+	if *bearerTokenFileF != "" {
+		handler = bearerTokenHandler(handler, *bearerTokenFileF, []string{*firehosePathF, "/-/healthy", "/-/ready"}, logger)
+	}
 
-	level.Error(logger).Log("error", http.ListenAndServe(*listenAddressF, nil))
+	// TLS and authentication settings of web configuration file apply to all handlers,
+	// including Firehose endpoint, health and readiness checks; there is no way to exempt them
+	srv := &http.Server{Handler: handler}
+	systemdSocket := false
+	webFlags := &web.FlagConfig{
+		WebListenAddresses: &[]string{*listenAddressF},
+		WebSystemdSocket:   &systemdSocket,
+		WebConfigFile:      webConfigFileF,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- web.ListenAndServe(srv, webFlags, logger)
+	}()
+
+	// shutdown gracefully on SIGINT or SIGTERM
+	term := make(chan os.Signal, 1)
+	signal.Notify(term, os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-errCh:
+		reloader.close()
+		level.Error(logger).Log("error", err)
+		os.Exit(1)
+
+	case s := <-term:
+		level.Info(logger).Log("msg", fmt.Sprintf("Received %s, shutting down...", s))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeoutF)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		level.Warn(logger).Log("msg", "Failed to wait for active requests.", "error", err)
+	}
+	reloader.close()
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		level.Error(logger).Log("error", err)
+	}
+	level.Info(logger).Log("msg", "Stopped.")
 }
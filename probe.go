@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/percona/rds_exporter/client"
+	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/sessions"
+)
+
+// Probe timeouts: used when Prometheus doesn't send scrape timeout header,
+// and subtracted from the header value to leave time for the response.
+const (
+	defaultProbeTimeout = 30 * time.Second
+	probeTimeoutOffset  = 500 * time.Millisecond
+)
+
+// errBadRequest wraps probe parameters errors.
+var errBadRequest = errors.New("bad request")
+
+// prober handles /probe requests that scrape a single instance.
+type prober struct {
+	reloader *reloader
+	client   *client.Client
+	trace    bool
+	logger   log.Logger
+	l        log.Logger
+
+	m       sync.Mutex
+	cfg     *config.Config        // configuration used for cached AWS configs
+	configs map[string]aws.Config // auth module name and region -> AWS config
+}
+
+func newProber(reloader *reloader, client *client.Client, logger log.Logger, trace bool) *prober {
+	return &prober{
+		reloader: reloader,
+		client:   client,
+		trace:    trace,
+		logger:   logger,
+		l:        log.With(logger, "component", "probe"),
+	}
+}
+
+// probeTimeout returns probe timeout for given request.
+func probeTimeout(req *http.Request) time.Duration {
+	v := req.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if v == "" {
+		return defaultProbeTimeout
+	}
+	seconds, err := strconv.ParseFloat(v, 64)
+	if err != nil || seconds <= 0 {
+		return defaultProbeTimeout
+	}
+
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > probeTimeoutOffset {
+		timeout -= probeTimeoutOffset
+	}
+	return timeout
+}
+
+// ServeHTTP implements http.Handler.
+func (p *prober) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	params := req.URL.Query()
+	region, instance, auth := params.Get("region"), params.Get("instance"), params.Get("auth")
+	if region == "" || instance == "" {
+		http.Error(rw, "region and instance parameters are required.", http.StatusBadRequest)
+		return
+	}
+	module := params.Get("module")
+	switch module {
+	case "":
+		module = "basic"
+	case "basic", "enhanced":
+		// nothing
+	default:
+		http.Error(rw, fmt.Sprintf("Unknown module %q.", module), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), probeTimeout(req))
+	defer cancel()
+
+	mSuccess := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_success",
+		Help: "Whether the probe was successful.",
+	})
+	mDuration := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_duration_seconds",
+		Help: "How long the probe took to complete, in seconds.",
+	})
+
+	start := time.Now()
+	metrics, err := p.probe(ctx, region, instance, module, auth)
+	mDuration.Set(time.Since(start).Seconds())
+	if errors.Is(err, errBadRequest) {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		level.Error(p.l).Log("msg", "Probe failed.", "region", region, "instance", instance, "module", module, "error", err)
+	} else {
+		mSuccess.Set(1)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(mSuccess, mDuration, constCollector(metrics))
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+	}).ServeHTTP(rw, req)
+}
+
+// probe returns metrics of a single instance for given module.
+// Metrics received before an error are returned too.
+func (p *prober) probe(ctx context.Context, region, instance, module, auth string) ([]prometheus.Metric, error) {
+	cfg, sess := p.reloader.current()
+	awsCfg, target, err := p.target(ctx, cfg, sess, region, instance, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	switch module {
+	case "enhanced":
+		if target.DisableEnhancedMetrics {
+			return nil, fmt.Errorf("%w: enhanced metrics are disabled for %s", errBadRequest, target)
+		}
+		return p.reloader.enhanced.Probe(ctx, awsCfg, *target)
+
+	default:
+		if target.DisableBasicMetrics {
+			return nil, fmt.Errorf("%w: basic metrics are disabled for %s", errBadRequest, target)
+		}
+
+		ch := make(chan prometheus.Metric)
+		go func() {
+			err = p.reloader.basic.Probe(ctx, awsCfg, *target, ch)
+			close(ch)
+		}()
+
+		var metrics []prometheus.Metric
+		for m := range ch {
+			metrics = append(metrics, m)
+		}
+		return metrics, err
+	}
+}
+
+// target returns AWS config and instance information for given parameters.
+// Configured or discovered instances are used as is if auth module is not specified;
+// other instances are described using given auth module, and rejected without it.
+func (p *prober) target(ctx context.Context, cfg *config.Config, sess *sessions.Sessions, region, instance, auth string) (aws.Config, *sessions.Instance, error) {
+	if auth == "" {
+		if awsCfg, target := sess.GetConfig(region, instance); target != nil {
+			return *awsCfg, target, nil
+		}
+		return aws.Config{}, nil, fmt.Errorf("%w: unknown instance %s/%s, auth parameter is required", errBadRequest, region, instance)
+	}
+
+	module, ok := cfg.AuthModules[auth]
+	if !ok {
+		return aws.Config{}, nil, fmt.Errorf("%w: unknown auth module %q", errBadRequest, auth)
+	}
+
+	awsCfg, err := p.awsConfig(cfg, auth, module.Instance(region, instance))
+	if err != nil {
+		return aws.Config{}, nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	target, err := sessions.Describe(ctx, awsCfg, module.Instance(region, instance))
+	if err != nil {
+		return aws.Config{}, nil, fmt.Errorf("failed to describe instance: %w", err)
+	}
+	return awsCfg, target, nil
+}
+
+// awsConfig returns cached AWS config for given auth module name and instance's region and credentials.
+// Cache is reset when configuration is reloaded.
+func (p *prober) awsConfig(cfg *config.Config, auth string, instance config.Instance) (aws.Config, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.cfg != cfg {
+		p.cfg = cfg
+		p.configs = make(map[string]aws.Config)
+	}
+
+	key := auth + "/" + instance.Region
+	if awsCfg, ok := p.configs[key]; ok {
+		return awsCfg, nil
+	}
+
+	awsCfg, err := sessions.LoadConfig(instance, p.client.HTTP(), p.trace, p.logger)
+	if err != nil {
+		return aws.Config{}, err
+	}
+	p.configs[key] = awsCfg
+	return awsCfg, nil
+}
+
+// constCollector is an unchecked collector for already collected metrics.
+type constCollector []prometheus.Metric
+
+// Describe implements prometheus.Collector.
+func (c constCollector) Describe(ch chan<- *prometheus.Desc) {
+	// unchecked collector
+}
+
+// Collect implements prometheus.Collector.
+func (c constCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c {
+		ch <- m
+	}
+}
+
+// check interfaces
+var (
+	_ http.Handler         = (*prober)(nil)
+	_ prometheus.Collector = constCollector(nil)
+)
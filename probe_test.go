@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/promlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/rds_exporter/client"
+)
+
+func TestProber(t *testing.T) {
+	// fake AWS endpoint that denies everything
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "text/xml")
+		rw.WriteHeader(http.StatusForbidden)
+		_, _ = rw.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>AccessDenied</Code><Message>denied</Message></Error></ErrorResponse>`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	t.Setenv("AWS_ENDPOINT_URL", srv.URL)
+	t.Setenv("AWS_CA_BUNDLE", "")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "aws_config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "aws_credentials"))
+
+	filename := filepath.Join(dir, "config.yml")
+	cfg := "---\ninstances: []\nauth_modules:\n  test:\n    aws_access_key: AKID\n    aws_secret_key: SECRET\n"
+	require.NoError(t, os.WriteFile(filename, []byte(cfg), 0666))
+
+	logger := promlog.New(&promlog.Config{})
+	c := client.New(logger)
+	r := newReloader(filename, c, logger, false)
+	require.NoError(t, r.init())
+	p := newProber(r, c, logger, false)
+
+	for query, expectedCode := range map[string]int{
+		"":                                       http.StatusBadRequest,
+		"region=us-east-1":                       http.StatusBadRequest,
+		"region=us-east-1&instance=a&module=foo": http.StatusBadRequest,
+		"region=us-east-1&instance=a":            http.StatusBadRequest,
+		"region=us-east-1&instance=a&auth=foo":   http.StatusBadRequest,
+		"region=us-east-1&instance=a&auth=test":  http.StatusOK,
+	} {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe?"+query, nil))
+		assert.Equal(t, expectedCode, rec.Code, query)
+
+		if rec.Code == http.StatusOK {
+			assert.Contains(t, strings.Split(rec.Body.String(), "\n"), "probe_success 0", query)
+		}
+	}
+}
+
+func TestProbeTimeout(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/probe", nil)
+	assert.Equal(t, defaultProbeTimeout, probeTimeout(req))
+
+	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "10")
+	assert.Equal(t, 9500*time.Millisecond, probeTimeout(req))
+}
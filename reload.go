@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/percona/rds_exporter/basic"
+	"github.com/percona/rds_exporter/client"
+	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/discovery"
+	"github.com/percona/rds_exporter/enhanced"
+	"github.com/percona/rds_exporter/sessions"
+)
+
+// reloader loads configuration file, discovers instances and keeps collectors up to date.
+type reloader struct {
+	filename string
+	client   *client.Client
+	trace    bool
+	logger   log.Logger
+	l        log.Logger
+
+	m        sync.Mutex
+	basic    *basic.Collector
+	enhanced *enhanced.Collector
+	cancel   context.CancelFunc // stops discovery
+
+	rw       sync.RWMutex
+	config   *config.Config
+	sessions *sessions.Sessions
+
+	mSuccess     prometheus.Gauge
+	mSuccessTime prometheus.Gauge
+}
+
+func newReloader(filename string, client *client.Client, logger log.Logger, trace bool) *reloader {
+	return &reloader{
+		filename: filename,
+		client:   client,
+		trace:    trace,
+		logger:   logger,
+		l:        log.With(logger, "component", "reloader"),
+
+		mSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "rds_exporter_config_last_reload_successful",
+			Help: "Whether the last configuration reload attempt was successful.",
+		}),
+		mSuccessTime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "rds_exporter_config_last_reload_success_timestamp_seconds",
+			Help: "Timestamp of the last successful configuration reload.",
+		}),
+	}
+}
+
+// load loads configuration file, discovers instances and creates sessions for them.
+func (r *reloader) load() (*config.Config, *discovery.Discoverer, discovery.Targets, *sessions.Sessions, error) {
+	cfg, err := config.Load(r.filename)
+	if err != nil {
+		return nil, nil, discovery.Targets{}, nil, fmt.Errorf("can't read configuration file: %w", err)
+	}
+
+	discoverer, err := discovery.New(cfg.Discovery, r.client.HTTP(), r.logger, r.trace)
+	if err != nil {
+		return nil, nil, discovery.Targets{}, nil, fmt.Errorf("can't create discoverer: %w", err)
+	}
+	discovered, err := discoverer.Discover(context.Background())
+	if err != nil {
+		return nil, nil, discovery.Targets{}, nil, fmt.Errorf("can't discover instances: %w", err)
+	}
+
+	sess, err := r.newSessions(cfg, discovered)
+	if err != nil {
+		return nil, nil, discovery.Targets{}, nil, fmt.Errorf("can't create sessions: %w", err)
+	}
+
+	return cfg, discoverer, discovered, sess, nil
+}
+
+// init performs the first load and creates collectors.
+func (r *reloader) init() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	cfg, discoverer, discovered, sess, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	r.basic = basic.New(cfg, sess, r.logger)
+	r.enhanced = enhanced.NewCollector(cfg, sess, r.logger)
+	r.setCurrent(cfg, sess)
+	r.startDiscovery(cfg, discoverer, discovered)
+
+	r.mSuccess.Set(1)
+	r.mSuccessTime.SetToCurrentTime()
+	return nil
+}
+
+// reload reloads configuration file and updates collectors.
+// Collectors are not changed if configuration can't be loaded.
+func (r *reloader) reload() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	cfg, discoverer, discovered, sess, err := r.load()
+	if err != nil {
+		r.mSuccess.Set(0)
+		return err
+	}
+
+	r.basic.Update(cfg, sess)
+	r.enhanced.Update(cfg, sess)
+	r.setCurrent(cfg, sess)
+	r.startDiscovery(cfg, discoverer, discovered)
+
+	r.mSuccess.Set(1)
+	r.mSuccessTime.SetToCurrentTime()
+	level.Info(r.l).Log("msg", "Configuration reloaded.")
+	return nil
+}
+
+// close stops discovery and collectors; in-flight AWS requests are canceled.
+func (r *reloader) close() {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+	r.basic.Close()
+	r.enhanced.Close()
+}
+
+// startDiscovery stops previous discovery loop and starts a new one that re-creates sessions
+// when discovered instances are changed. It should be called with locked mutex.
+func (r *reloader) startDiscovery(cfg *config.Config, discoverer *discovery.Discoverer, discovered discovery.Targets) {
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+	if len(cfg.Discovery.Jobs) == 0 {
+		return
+	}
+
+	interval := cfg.Discovery.RefreshInterval
+	if interval <= 0 {
+		interval = discovery.DefaultRefreshInterval
+	}
+	level.Info(r.l).Log("msg", fmt.Sprintf("Discovering instances every %s.", interval))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	ch := make(chan discovery.Targets)
+	go func() {
+		discoverer.Start(ctx, interval, discovered, ch)
+		close(ch)
+	}()
+	go func() {
+		for discovered := range ch {
+			r.m.Lock()
+			// skip instances from the stopped discovery loop
+			if ctx.Err() == nil {
+				r.updateDiscovered(cfg, discovered)
+			}
+			r.m.Unlock()
+		}
+	}()
+}
+
+// newSessions creates sessions for configured and discovered instances and clusters.
+func (r *reloader) newSessions(cfg *config.Config, discovered discovery.Targets) (*sessions.Sessions, error) {
+	targets := discovery.Merge(cfg, discovered)
+	return sessions.New(targets.Instances, targets.Clusters, r.client.HTTP(), r.logger, r.trace)
+}
+
+// updateDiscovered re-creates sessions for given discovered targets. It should be called with locked mutex.
+func (r *reloader) updateDiscovered(cfg *config.Config, discovered discovery.Targets) {
+	sess, err := r.newSessions(cfg, discovered)
+	if err != nil {
+		level.Error(r.l).Log("msg", "Can't create sessions", "error", err)
+		return
+	}
+	r.basic.Update(cfg, sess)
+	r.enhanced.Update(cfg, sess)
+	r.setCurrent(cfg, sess)
+}
+
+// setCurrent saves configuration and sessions used by collectors.
+func (r *reloader) setCurrent(cfg *config.Config, sess *sessions.Sessions) {
+	r.rw.Lock()
+	r.config = cfg
+	r.sessions = sess
+	r.rw.Unlock()
+}
+
+// current returns configuration and sessions used by collectors.
+// Unlike other methods, it doesn't wait for running reload or discovery.
+func (r *reloader) current() (*config.Config, *sessions.Sessions) {
+	r.rw.RLock()
+	defer r.rw.RUnlock()
+	return r.config, r.sessions
+}
+
+// ready returns true if sessions are created and the first enhanced metrics scrapes after startup are finished.
+// Like current, it doesn't wait for running reload or discovery.
+func (r *reloader) ready() bool {
+	_, sess := r.current()
+	return sess != nil && r.enhanced != nil && r.enhanced.Ready()
+}
+
+// Describe implements prometheus.Collector.
+func (r *reloader) Describe(ch chan<- *prometheus.Desc) {
+	r.mSuccess.Describe(ch)
+	r.mSuccessTime.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (r *reloader) Collect(ch chan<- prometheus.Metric) {
+	r.mSuccess.Collect(ch)
+	r.mSuccessTime.Collect(ch)
+}
+
+// check interfaces
+var (
+	_ prometheus.Collector = (*reloader)(nil)
+)
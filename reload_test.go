@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/promlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/rds_exporter/client"
+)
+
+func TestReloader(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(filename, []byte("---\ninstances: []\n"), 0666))
+
+	logger := promlog.New(&promlog.Config{})
+	r := newReloader(filename, client.New(logger), logger, false)
+	require.NoError(t, r.init())
+	assert.Equal(t, float64(1), testutil.ToFloat64(r.mSuccess))
+
+	require.NoError(t, os.WriteFile(filename, []byte("instances: ["), 0666))
+	assert.Error(t, r.reload())
+	assert.Equal(t, float64(0), testutil.ToFloat64(r.mSuccess))
+
+	require.NoError(t, os.WriteFile(filename, []byte("---\ninstances: []\n"), 0666))
+	assert.NoError(t, r.reload())
+	assert.Equal(t, float64(1), testutil.ToFloat64(r.mSuccess))
+}
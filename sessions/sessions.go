@@ -3,8 +3,10 @@ package sessions
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -13,13 +15,34 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go/middleware"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 
+	"github.com/percona/rds_exporter/client"
 	"github.com/percona/rds_exporter/config"
 )
 
+// InstanceInfo contains RDS instance metadata returned by DescribeDBInstances.
+type InstanceInfo struct {
+	Engine                string
+	EngineVersion         string
+	InstanceClass         string
+	StorageType           string
+	MultiAZ               bool
+	AvailabilityZone      string
+	ClusterID             string
+	ParameterGroup        string
+	CACertificate         string
+	AllocatedStorage      int // GiB
+	MaxAllocatedStorage   int // GiB, zero if storage autoscaling is disabled
+	IOPS                  int
+	StorageThroughput     int // MiB/s
+	BackupRetentionPeriod int // days
+}
+
 // Instance represents a single RDS instance information in runtime.
 type Instance struct {
 	Region                     string
@@ -29,6 +52,9 @@ type Instance struct {
 	ResourceID                 string
 	Labels                     map[string]string
 	EnhancedMonitoringInterval time.Duration
+	EnhancedInterval           time.Duration // configured override of EnhancedMonitoringInterval for polling, may be zero
+	Info                       InstanceInfo
+	Basic                      config.Basic
 }
 
 func (i Instance) String() string {
@@ -39,24 +65,53 @@ func (i Instance) String() string {
 	return res
 }
 
+// Cluster represents a single Aurora cluster information in runtime.
+type Cluster struct {
+	Region  string
+	Cluster string
+	Labels  map[string]string
+	Basic   config.Basic
+}
+
+func (c Cluster) String() string {
+	return c.Region + "/" + c.Cluster
+}
+
 // Sessions is a pool of AWS configs per region.
 type Sessions struct {
 	sessions map[string][]Instance
+	clusters map[string][]Cluster
+	skipped  map[string][]Instance // instances without resource ID
 	Configs  map[string]aws.Config
 }
 
+// sessionKey returns key of AWS config for given instance's region and credentials:
+// region and access key, followed by role ARN and IRSA flag if they are set.
+func sessionKey(instance config.Instance) string {
+	key := instance.Region + "/" + instance.AWSAccessKey
+	if instance.AWSRoleArn != "" {
+		key += "/" + instance.AWSRoleArn
+	}
+	if instance.IRSAEnabled {
+		key += "/irsa"
+	}
+	return key
+}
+
 // New creates a new sessions pool for given configuration.
-func New(instances []config.Instance, client *http.Client, logger log.Logger, trace bool) (*Sessions, error) {
+func New(instances []config.Instance, clusters []config.Cluster, client *http.Client, logger log.Logger, trace bool) (*Sessions, error) {
 	logger = log.With(logger, "component", "sessions")
 	level.Info(logger).Log("msg", "Creating sessions...")
 
 	res := &Sessions{
 		sessions: make(map[string][]Instance),
+		clusters: make(map[string][]Cluster),
+		skipped:  make(map[string][]Instance),
 		Configs:  make(map[string]aws.Config),
 	}
 
 	for _, instance := range instances {
-		key := instance.Region + "/" + instance.AWSAccessKey
+		key := sessionKey(instance)
 		if _, exists := res.Configs[key]; exists {
 			res.sessions[key] = append(res.sessions[key], Instance{
 				Region:                 instance.Region,
@@ -64,11 +119,13 @@ func New(instances []config.Instance, client *http.Client, logger log.Logger, tr
 				Labels:                 instance.Labels,
 				DisableBasicMetrics:    instance.DisableBasicMetrics,
 				DisableEnhancedMetrics: instance.DisableEnhancedMetrics,
+				EnhancedInterval:       instance.EnhancedInterval,
+				Basic:                  instance.Basic,
 			})
 			continue
 		}
 
-		cfg, err := loadAWSConfig(instance, client, trace, logger)
+		cfg, err := LoadConfig(instance, client, trace, logger)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load AWS config: %w", err)
 		}
@@ -80,11 +137,42 @@ func New(instances []config.Instance, client *http.Client, logger log.Logger, tr
 			Labels:                 instance.Labels,
 			DisableBasicMetrics:    instance.DisableBasicMetrics,
 			DisableEnhancedMetrics: instance.DisableEnhancedMetrics,
+			EnhancedInterval:       instance.EnhancedInterval,
+			Basic:                  instance.Basic,
+		})
+	}
+
+	for _, cluster := range clusters {
+		creds := config.Instance{
+			Region:       cluster.Region,
+			AWSAccessKey: cluster.AWSAccessKey,
+			AWSSecretKey: cluster.AWSSecretKey,
+			AWSRoleArn:   cluster.AWSRoleArn,
+			IRSAEnabled:  cluster.IRSAEnabled,
+		}
+		key := sessionKey(creds)
+		if _, exists := res.Configs[key]; !exists {
+			cfg, err := LoadConfig(creds, client, trace, logger)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load AWS config: %w", err)
+			}
+			res.Configs[key] = cfg
+		}
+
+		res.clusters[key] = append(res.clusters[key], Cluster{
+			Region:  cluster.Region,
+			Cluster: cluster.Cluster,
+			Labels:  cluster.Labels,
+			Basic:   cluster.Basic,
 		})
 	}
 
 	// add resource ID to all instances
 	for key, cfg := range res.Configs {
+		if len(res.sessions[key]) == 0 {
+			continue
+		}
+
 		svc := rds.NewFromConfig(cfg)
 		var marker *string
 		for {
@@ -99,12 +187,7 @@ func New(instances []config.Instance, client *http.Client, logger log.Logger, tr
 			for _, dbInstance := range output.DBInstances {
 				for i, instance := range res.sessions[key] {
 					if dbInstance.DBInstanceIdentifier != nil && *dbInstance.DBInstanceIdentifier == instance.Instance {
-						if dbInstance.DbiResourceId != nil {
-							res.sessions[key][i].ResourceID = *dbInstance.DbiResourceId
-						}
-						if dbInstance.MonitoringInterval != nil {
-							res.sessions[key][i].EnhancedMonitoringInterval = time.Duration(*dbInstance.MonitoringInterval) * time.Second
-						}
+						res.sessions[key][i].update(&dbInstance)
 					}
 				}
 			}
@@ -120,6 +203,7 @@ func New(instances []config.Instance, client *http.Client, logger log.Logger, tr
 		for _, instance := range instances {
 			if instance.ResourceID == "" {
 				level.Error(logger).Log("msg", fmt.Sprintf("Skipping %s - can't determine resourceID.", instance))
+				res.skipped[key] = append(res.skipped[key], instance)
 				continue
 			}
 			newInstances = append(newInstances, instance)
@@ -127,25 +211,104 @@ func New(instances []config.Instance, client *http.Client, logger log.Logger, tr
 		res.sessions[key] = newInstances
 	}
 
-	// remove configs without instances
+	// remove configs without instances and clusters
 	for key := range res.Configs {
 		if len(res.sessions[key]) == 0 {
-			delete(res.Configs, key)
 			delete(res.sessions, key)
+			if len(res.clusters[key]) == 0 {
+				delete(res.Configs, key)
+			}
 		}
 	}
 
-	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	res.WriteTable(os.Stderr)
+
+	level.Info(logger).Log("msg", fmt.Sprintf("Using %d session configs.", len(res.Configs)))
+	return res, nil
+}
+
+// Describe returns runtime information for a single instance using given AWS config.
+func Describe(ctx context.Context, cfg aws.Config, instance config.Instance) (*Instance, error) {
+	output, err := rds.NewFromConfig(cfg).DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{
+		DBInstanceIdentifier: aws.String(instance.Instance),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.DBInstances) != 1 {
+		return nil, fmt.Errorf("expected 1 instance %s, got %d", instance, len(output.DBInstances))
+	}
+
+	res := &Instance{
+		Region:                 instance.Region,
+		Instance:               instance.Instance,
+		Labels:                 instance.Labels,
+		DisableBasicMetrics:    instance.DisableBasicMetrics,
+		DisableEnhancedMetrics: instance.DisableEnhancedMetrics,
+		EnhancedInterval:       instance.EnhancedInterval,
+		Basic:                  instance.Basic,
+	}
+	res.update(&output.DBInstances[0])
+	return res, nil
+}
+
+// update sets resource ID, enhanced monitoring interval and metadata from given DescribeDBInstances result.
+func (i *Instance) update(dbInstance *types.DBInstance) {
+	if dbInstance.DbiResourceId != nil {
+		i.ResourceID = *dbInstance.DbiResourceId
+	}
+	if dbInstance.MonitoringInterval != nil {
+		i.EnhancedMonitoringInterval = time.Duration(*dbInstance.MonitoringInterval) * time.Second
+	}
+	i.Info = makeInstanceInfo(dbInstance)
+}
+
+// makeInstanceInfo returns metadata for given DescribeDBInstances result.
+func makeInstanceInfo(dbInstance *types.DBInstance) InstanceInfo {
+	parameterGroups := make([]string, 0, len(dbInstance.DBParameterGroups))
+	for _, pg := range dbInstance.DBParameterGroups {
+		parameterGroups = append(parameterGroups, aws.ToString(pg.DBParameterGroupName))
+	}
+
+	return InstanceInfo{
+		Engine:                aws.ToString(dbInstance.Engine),
+		EngineVersion:         aws.ToString(dbInstance.EngineVersion),
+		InstanceClass:         aws.ToString(dbInstance.DBInstanceClass),
+		StorageType:           aws.ToString(dbInstance.StorageType),
+		MultiAZ:               aws.ToBool(dbInstance.MultiAZ),
+		AvailabilityZone:      aws.ToString(dbInstance.AvailabilityZone),
+		ClusterID:             aws.ToString(dbInstance.DBClusterIdentifier),
+		ParameterGroup:        strings.Join(parameterGroups, ","),
+		CACertificate:         aws.ToString(dbInstance.CACertificateIdentifier),
+		AllocatedStorage:      int(aws.ToInt32(dbInstance.AllocatedStorage)),
+		MaxAllocatedStorage:   int(aws.ToInt32(dbInstance.MaxAllocatedStorage)),
+		IOPS:                  int(aws.ToInt32(dbInstance.Iops)),
+		StorageThroughput:     int(aws.ToInt32(dbInstance.StorageThroughput)),
+		BackupRetentionPeriod: int(aws.ToInt32(dbInstance.BackupRetentionPeriod)),
+	}
+}
+
+// WriteTable writes table of instances and clusters to given writer.
+func (s *Sessions) WriteTable(out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
 	fmt.Fprintf(w, "Region\tInstance\tResource ID\tInterval\n")
-	for _, instances := range res.sessions {
+	for _, instances := range s.sessions {
 		for _, instance := range instances {
 			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", instance.Region, instance.Instance, instance.ResourceID, instance.EnhancedMonitoringInterval)
 		}
 	}
 	_ = w.Flush()
 
-	level.Info(logger).Log("msg", fmt.Sprintf("Using %d session configs.", len(res.Configs)))
-	return res, nil
+	if len(s.clusters) > 0 {
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Region\tCluster\n")
+		for _, clusters := range s.clusters {
+			for _, cluster := range clusters {
+				fmt.Fprintf(w, "%s\t%s\n", cluster.Region, cluster.Cluster)
+			}
+		}
+		_ = w.Flush()
+	}
 }
 
 // GetConfig returns AWS config and full instance information for given region and instance.
@@ -166,11 +329,53 @@ func (s *Sessions) AllSessions() map[string][]Instance {
 	return s.sessions
 }
 
-// Internal helper
-func loadAWSConfig(instance config.Instance, client *http.Client, trace bool, logger log.Logger) (aws.Config, error) {
+// AllClusters returns all AWS configs and clusters.
+func (s *Sessions) AllClusters() map[string][]Cluster {
+	return s.clusters
+}
+
+// Skipped returns instances skipped because their resource IDs can't be determined, by session key.
+func (s *Sessions) Skipped() map[string][]Instance {
+	return s.skipped
+}
+
+// LoadConfig returns AWS config for given instance's region and credentials.
+// Requests are rate limited per AWS account when it is known from credentials
+// (assumed roles, IRSA, or AWS_ACCOUNT_ID environment variable), and per access key otherwise.
+func LoadConfig(instance config.Instance, client *http.Client, trace bool, logger log.Logger) (aws.Config, error) {
+	cfg, err := loadConfig(instance, client, trace, logger)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	// added after STS client for assumed role is created, so that credentials are not retrieved recursively
+	cfg.APIOptions = append(cfg.APIOptions, accountLimiterKey(cfg.Credentials))
+	return cfg, nil
+}
+
+// accountLimiterKey returns AWS SDK API option that sets client's rate limiter key to AWS account ID
+// of given credentials, if it is known.
+func accountLimiterKey(provider aws.CredentialsProvider) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("RDSExporterLimiterKey",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				if provider != nil {
+					// credentials are cached; retrieval error is returned by the signer later
+					if creds, err := provider.Retrieve(ctx); err == nil && creds.AccountID != "" {
+						ctx = client.WithLimiterKey(ctx, "account/"+creds.AccountID)
+					}
+				}
+				return next.HandleInitialize(ctx, in)
+			}), middleware.After)
+	}
+}
+
+// loadConfig returns AWS config for given instance's region and credentials.
+func loadConfig(instance config.Instance, client *http.Client, trace bool, logger log.Logger) (aws.Config, error) {
 	options := []func(*awsConfig.LoadOptions) error{
 		awsConfig.WithRegion(instance.Region),
 		awsConfig.WithHTTPClient(client),
+		awsConfig.WithRetryMaxAttempts(1), // requests are retried by client's transport
 	}
 
 	if instance.IRSAEnabled {
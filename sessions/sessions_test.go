@@ -1,12 +1,19 @@
 package sessions
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
 	"github.com/prometheus/common/promlog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -33,9 +40,23 @@ func TestSession(t *testing.T) {
 
 	logger := promlog.New(&promlog.Config{})
 	client := client.New(logger)
-	sessions, err := New(cfg.Instances, client.HTTP(), logger, false)
+	sessions, err := New(cfg.Instances, cfg.Clusters, client.HTTP(), logger, false)
 	require.NoError(t, err)
 
+	// check only stable metadata fields, then clear it for comparison below
+	engines := map[string]string{
+		"autotest-aurora-mysql-56": "aurora",
+		"autotest-psql-10":         "postgres",
+		"autotest-mysql-57":        "mysql",
+		"autotest-aurora-psql-11":  "aurora-postgresql",
+	}
+	for _, instances := range sessions.sessions {
+		for i := range instances {
+			assert.Equal(t, engines[instances[i].Instance], instances[i].Info.Engine, instances[i].Instance)
+			instances[i].Info = InstanceInfo{}
+		}
+	}
+
 	am56s, am56i := sessions.GetConfig("us-east-1", "autotest-aurora-mysql-56")
 	p10s, p10i := sessions.GetConfig("us-east-1", "autotest-psql-10")
 	m57s, m57i := sessions.GetConfig("us-west-2", "autotest-mysql-57")
@@ -93,3 +114,56 @@ func TestSession(t *testing.T) {
 		"us-west-2/": {m57iExpected, ap11iExpected},
 	}, all)
 }
+
+func TestSessionKey(t *testing.T) {
+	static := config.Instance{Region: "us-east-1", AWSAccessKey: "AKID"}
+	role1 := config.Instance{Region: "us-east-1", AWSRoleArn: "arn:aws:iam::123456789012:role/one"}
+	role2 := config.Instance{Region: "us-east-1", AWSRoleArn: "arn:aws:iam::123456789012:role/two"}
+	irsa := config.Instance{Region: "us-east-1", IRSAEnabled: true}
+	ambient := config.Instance{Region: "us-east-1"}
+
+	assert.Equal(t, "us-east-1/AKID", sessionKey(static))
+	keys := make(map[string]struct{})
+	for _, i := range []config.Instance{static, role1, role2, irsa, ambient} {
+		keys[sessionKey(i)] = struct{}{}
+	}
+	assert.Len(t, keys, 5)
+}
+
+func TestAccountLimiterKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(rw, `<DescribeDBInstancesResponse><DescribeDBInstancesResult><DBInstances/></DescribeDBInstancesResult></DescribeDBInstancesResponse>`)
+	}))
+	defer srv.Close()
+
+	// a single request per bucket
+	c := client.NewWithConfig(client.Config{MaxAttempts: 1, RateLimit: 0.001, RateBurst: 1}, promlog.New(&promlog.Config{}))
+	describe := func(accessKey, accountID string) error {
+		cfg := aws.Config{
+			Region:       "us-east-1",
+			HTTPClient:   c.HTTP(),
+			BaseEndpoint: aws.String(srv.URL),
+			Credentials: credentials.StaticCredentialsProvider{Value: aws.Credentials{
+				AccessKeyID:     accessKey,
+				SecretAccessKey: "secret",
+				AccountID:       accountID,
+			}},
+			RetryMaxAttempts: 1,
+		}
+		cfg.APIOptions = append(cfg.APIOptions, accountLimiterKey(cfg.Credentials))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		_, err := rds.NewFromConfig(cfg).DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{})
+		return err
+	}
+
+	// different access keys of the same account share a bucket
+	require.NoError(t, describe("AKID1", "123456789012"))
+	assert.Error(t, describe("AKID2", "123456789012"))
+
+	// access keys of unknown accounts have their own buckets
+	require.NoError(t, describe("AKID3", ""))
+	require.NoError(t, describe("AKID4", ""))
+}
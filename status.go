@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/percona/rds_exporter/health"
+	"github.com/percona/rds_exporter/sessions"
+)
+
+// instanceStatus contains runtime information and the latest scrape results of a single instance.
+type instanceStatus struct {
+	Region                     string          `json:"region"`
+	Instance                   string          `json:"instance"`
+	ResourceID                 string          `json:"resource_id"`
+	EnhancedMonitoringInterval string          `json:"enhanced_monitoring_interval"`
+	EnhancedInterval           string          `json:"enhanced_interval,omitempty"` // configured override
+	Skipped                    string          `json:"skipped,omitempty"`           // reason why instance is not scraped
+	BasicEnabled               bool            `json:"basic_enabled"`
+	EnhancedEnabled            bool            `json:"enhanced_enabled"`
+	Basic                      *health.Status  `json:"basic,omitempty"`
+	Enhanced                   *health.Status  `json:"enhanced,omitempty"`
+	LatestEvent                time.Time       `json:"latest_event,omitzero"`
+	LastMessage                json.RawMessage `json:"last_message,omitempty"` // raw enhanced monitoring message
+}
+
+// sessionStatus contains instances and clusters sharing the same AWS config.
+type sessionStatus struct {
+	Key       string           `json:"key"` // region and masked access key, see maskSessionKey
+	Instances []instanceStatus `json:"instances"`
+	Clusters  []string         `json:"clusters,omitempty"`
+}
+
+// makeStatus returns status of all sessions, sorted by key.
+func makeStatus(r *reloader) []sessionStatus {
+	_, sess := r.current()
+	if sess == nil {
+		return nil
+	}
+
+	keys := make(map[string]struct{})
+	for key := range sess.AllSessions() {
+		keys[key] = struct{}{}
+	}
+	for key := range sess.AllClusters() {
+		keys[key] = struct{}{}
+	}
+	for key := range sess.Skipped() {
+		keys[key] = struct{}{}
+	}
+
+	res := make([]sessionStatus, 0, len(keys))
+	for key := range keys {
+		s := sessionStatus{
+			Key:       maskSessionKey(key),
+			Instances: make([]instanceStatus, 0, len(sess.AllSessions()[key])+len(sess.Skipped()[key])),
+		}
+		for _, instance := range sess.AllSessions()[key] {
+			s.Instances = append(s.Instances, makeInstanceStatus(r, instance))
+		}
+		for _, instance := range sess.Skipped()[key] {
+			is := makeInstanceStatus(r, instance)
+			is.Skipped = "can't determine resource ID"
+			s.Instances = append(s.Instances, is)
+		}
+		for _, cluster := range sess.AllClusters()[key] {
+			s.Clusters = append(s.Clusters, cluster.Cluster)
+		}
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Key < res[j].Key })
+	return res
+}
+
+// maskSessionKey returns session key with all but the last 4 characters of access key masked.
+func maskSessionKey(key string) string {
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) < 2 || parts[1] == "" {
+		return key
+	}
+	accessKey := parts[1]
+	if len(accessKey) > 4 {
+		parts[1] = strings.Repeat("*", len(accessKey)-4) + accessKey[len(accessKey)-4:]
+	} else {
+		parts[1] = strings.Repeat("*", len(accessKey))
+	}
+	return strings.Join(parts, "/")
+}
+
+// makeInstanceStatus returns status of a single instance.
+func makeInstanceStatus(r *reloader, instance sessions.Instance) instanceStatus {
+	res := instanceStatus{
+		Region:                     instance.Region,
+		Instance:                   instance.Instance,
+		ResourceID:                 instance.ResourceID,
+		EnhancedMonitoringInterval: instance.EnhancedMonitoringInterval.String(),
+		BasicEnabled:               !instance.DisableBasicMetrics,
+		EnhancedEnabled:            !instance.DisableEnhancedMetrics,
+	}
+	if instance.EnhancedInterval != 0 {
+		res.EnhancedInterval = instance.EnhancedInterval.String()
+	}
+
+	if s, ok := r.basic.Status(instance.Region, instance.Instance); ok {
+		res.Basic = &s
+	}
+	if s, ok := r.enhanced.Status(instance.Region, instance.Instance); ok {
+		res.Enhanced = &s
+	}
+	if instance.ResourceID != "" {
+		if timestamp, message, ok := r.enhanced.LastEvent(instance.ResourceID); ok {
+			res.LatestEvent = timestamp
+			if json.Valid([]byte(message)) {
+				res.LastMessage = json.RawMessage(message)
+			}
+		}
+	}
+	return res
+}
+
+var statusTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"time": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.UTC().Format(time.RFC3339)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>RDS Exporter status</title>
+</head>
+<body>
+<h1>RDS Exporter status</h1>
+<p><a href="?format=json">JSON</a></p>
+{{- range .Sessions }}
+<h2>{{ .Key }}</h2>
+{{- if .Clusters }}
+<p>Clusters: {{ range $i, $c := .Clusters }}{{ if $i }}, {{ end }}{{ $c }}{{ end }}</p>
+{{- end }}
+<table border="1" cellpadding="4">
+<tr>
+<th>Instance</th><th>Resource ID</th><th>Interval</th>
+<th>Basic</th><th>Basic last scrape</th><th>Basic last error</th>
+<th>Enhanced</th><th>Enhanced last scrape</th><th>Enhanced last error</th><th>Latest event</th>
+</tr>
+{{- range .Instances }}
+<tr>
+<td>{{ .Region }}/{{ .Instance }}</td>
+<td>{{ if .Skipped }}skipped: {{ .Skipped }}{{ else }}{{ .ResourceID }}{{ end }}</td>
+<td>{{ .EnhancedMonitoringInterval }}{{ if .EnhancedInterval }} (polled every {{ .EnhancedInterval }}){{ end }}</td>
+<td>{{ if .BasicEnabled }}enabled{{ else }}disabled{{ end }}</td>
+{{- with .Basic }}
+<td>{{ time .LastScrape }} ({{ if .Success }}success{{ else }}failure{{ end }})</td>
+<td>{{ .LastError }} {{ if .LastError }}({{ time .LastErrorTime }}){{ end }}</td>
+{{- else }}
+<td>-</td><td></td>
+{{- end }}
+<td>{{ if .EnhancedEnabled }}enabled{{ else }}disabled{{ end }}</td>
+{{- with .Enhanced }}
+<td>{{ time .LastScrape }} ({{ if .Success }}success{{ else }}failure{{ end }})</td>
+<td>{{ .LastError }} {{ if .LastError }}({{ time .LastErrorTime }}){{ end }}</td>
+{{- else }}
+<td>-</td><td></td>
+{{- end }}
+<td>{{ time .LatestEvent }}{{ if .LastMessage }}<details><summary>message</summary><pre>{{ printf "%s" .LastMessage }}</pre></details>{{ end }}</td>
+</tr>
+{{- end }}
+</table>
+{{- end }}
+</body>
+</html>
+`))
+
+// statusHandler returns HTTP handler for the status page with sessions, instances and their latest scrape results.
+// JSON is returned for format=json parameter or Accept header.
+func statusHandler(reloader *reloader, logger log.Logger) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		status := makeStatus(reloader)
+		if status == nil {
+			status = []sessionStatus{}
+		}
+
+		if req.URL.Query().Get("format") == "json" || strings.Contains(req.Header.Get("Accept"), "application/json") {
+			rw.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(rw)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(status); err != nil {
+				level.Error(logger).Log("msg", "Failed to encode status.", "error", err)
+			}
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := statusTemplate.Execute(&buf, map[string]interface{}{"Sessions": status}); err != nil {
+			level.Error(logger).Log("msg", "Failed to render status page.", "error", err)
+			http.Error(rw, "Failed to render status page.", http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = buf.WriteTo(rw)
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// readBearerToken reads bearer token from given file.
+func readBearerToken(filename string) (string, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("%s: empty bearer token", filename)
+	}
+	return token, nil
+}
+
+// bearerTokenHandler returns HTTP handler that requires "Authorization: Bearer <token>" header
+// with the token from given file for all paths except exempted ones.
+// The file is re-read for every request, so the token is updated without restart.
+func bearerTokenHandler(handler http.Handler, tokenFile string, exempt []string, logger log.Logger) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		for _, path := range exempt {
+			if req.URL.Path == path {
+				handler.ServeHTTP(rw, req)
+				return
+			}
+		}
+
+		token, err := readBearerToken(tokenFile)
+		if err != nil {
+			level.Error(logger).Log("msg", "Failed to read bearer token.", "error", err)
+			http.Error(rw, "Failed to read bearer token.", http.StatusInternalServerError)
+			return
+		}
+
+		const prefix = "Bearer "
+		auth := req.Header.Get("Authorization")
+		if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) ||
+			subtle.ConstantTimeCompare([]byte(auth[len(prefix):]), []byte(token)) != 1 {
+			rw.Header().Set("WWW-Authenticate", `Bearer realm="rds_exporter"`)
+			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(rw, req)
+	})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/common/promlog"
+	"github.com/prometheus/exporter-toolkit/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebConfig(t *testing.T) {
+	// bcrypt hash of "alice123"
+	webConfig := "---\nbasic_auth_users:\n  alice: $2y$12$1DpfPeqF9HzHJt.EWswy1exHluGfbhnn3yXhR7Xes6m3WJqFg0Wby\n"
+	filename := filepath.Join(t.TempDir(), "web.yml")
+	require.NoError(t, os.WriteFile(filename, []byte(webConfig), 0666))
+	require.NoError(t, web.Validate(filename))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/-/healthy", healthyHandler)
+	mux.HandleFunc("/firehose", func(rw http.ResponseWriter, req *http.Request) {})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &http.Server{Handler: mux}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- web.Serve(l, srv, &web.FlagConfig{WebConfigFile: &filename}, promlog.New(&promlog.Config{}))
+	}()
+	defer func() {
+		require.NoError(t, srv.Close())
+		assert.True(t, errors.Is(<-errCh, http.ErrServerClosed))
+	}()
+
+	get := func(path, username, password string) int {
+		req, err := http.NewRequest(http.MethodGet, "http://"+l.Addr().String()+path, nil)
+		require.NoError(t, err)
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// authentication applies to all endpoints, including health check and Firehose endpoint
+	for _, path := range []string{"/-/healthy", "/firehose"} {
+		assert.Equal(t, http.StatusUnauthorized, get(path, "", ""), path)
+		assert.Equal(t, http.StatusUnauthorized, get(path, "alice", "wrong"), path)
+		assert.Equal(t, http.StatusOK, get(path, "alice", "alice123"), path)
+	}
+
+	// invalid configuration is rejected
+	require.NoError(t, os.WriteFile(filename, []byte("---\nbasic_auth_users:\n  alice: alice123\n"), 0666))
+	assert.Error(t, web.Validate(filename))
+}
+
+func TestBearerToken(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(filename, []byte("secret\n"), 0600))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/basic", func(rw http.ResponseWriter, req *http.Request) {})
+	mux.HandleFunc("/-/healthy", healthyHandler)
+	handler := bearerTokenHandler(mux, filename, []string{"/-/healthy"}, promlog.New(&promlog.Config{}))
+
+	get := func(path, auth string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, get("/basic", ""))
+	assert.Equal(t, http.StatusUnauthorized, get("/basic", "Bearer wrong"))
+	assert.Equal(t, http.StatusUnauthorized, get("/basic", "Basic c2VjcmV0"))
+	assert.Equal(t, http.StatusOK, get("/basic", "Bearer secret"))
+	assert.Equal(t, http.StatusOK, get("/-/healthy", ""))
+
+	// token is re-read for every request
+	require.NoError(t, os.WriteFile(filename, []byte("new"), 0600))
+	assert.Equal(t, http.StatusUnauthorized, get("/basic", "Bearer secret"))
+	assert.Equal(t, http.StatusOK, get("/basic", "Bearer new"))
+
+	require.NoError(t, os.WriteFile(filename, nil, 0600))
+	_, err := readBearerToken(filename)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, get("/basic", "Bearer "))
+}